@@ -19,6 +19,8 @@ const (
 	LOGICAL_OR_EXP  ExpType = "logical_or"
 	LOGICAL_AND_EXP ExpType = "logical_and"
 	CALL_EXP        ExpType = "call"
+	GET_EXP         ExpType = "get"
+	SET_EXP         ExpType = "set"
 )
 
 type Expression interface {
@@ -37,6 +39,8 @@ type Visitor interface {
 	VisitAssignment(exp *Assignment) any
 	VisitLogical(exp *Logical) any
 	VisitCall(exp *Call) any
+	VisitGet(exp *Get) any
+	VisitSet(exp *Set) any
 }
 
 type Literal struct {
@@ -177,8 +181,17 @@ func (exp *Ternary) Accept(v Visitor) any {
 	return v.VisitTernary(exp)
 }
 
+// Binding tells where a resolved name lives at runtime: `Depth` environments up from the current one, at index `Slot`.
+// Globals are not resolved to a slot, they are looked up by name.
+type Binding struct {
+	Local bool
+	Depth int
+	Slot  int
+}
+
 type Variable struct {
-	Name token.Token
+	Name    token.Token
+	Binding Binding // Filled in by the resolver.
 }
 
 func NewVariable(name token.Token) *Variable {
@@ -198,8 +211,9 @@ func (v *Variable) Accept(visitor Visitor) any {
 }
 
 type Assignment struct {
-	Name  token.Token
-	Value Expression
+	Name    token.Token
+	Value   Expression
+	Binding Binding // Filled in by the resolver.
 }
 
 func NewAssignment(name token.Token, value Expression) *Assignment {
@@ -298,3 +312,48 @@ func (exp *Call) String() string {
 	out.WriteString(")")
 	return parenthesize(exp.Type(), out.String())
 }
+
+// Get reads a property of an object, e.g. `order.total`.
+type Get struct {
+	Object Expression
+	Name   token.Token
+}
+
+func NewGet(object Expression, name token.Token) *Get {
+	return &Get{Object: object, Name: name}
+}
+
+func (exp *Get) Type() ExpType {
+	return GET_EXP
+}
+
+func (exp *Get) Accept(v Visitor) any {
+	return v.VisitGet(exp)
+}
+
+func (exp *Get) String() string {
+	return parenthesize(exp.Type(), exp.Object.String()+" . "+exp.Name.Lexeme)
+}
+
+// Set writes a property of an object, e.g. `order.total = 12`.
+type Set struct {
+	Object Expression
+	Name   token.Token
+	Value  Expression
+}
+
+func NewSet(object Expression, name token.Token, value Expression) *Set {
+	return &Set{Object: object, Name: name, Value: value}
+}
+
+func (exp *Set) Type() ExpType {
+	return SET_EXP
+}
+
+func (exp *Set) Accept(v Visitor) any {
+	return v.VisitSet(exp)
+}
+
+func (exp *Set) String() string {
+	return parenthesize(exp.Type(), exp.Object.String()+" . "+exp.Name.Lexeme+" "+exp.Value.String())
+}
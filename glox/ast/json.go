@@ -0,0 +1,465 @@
+package ast
+
+import (
+	"encoding/json"
+	"fmt"
+	"glox/token"
+)
+
+// Kinds of the statements in the JSON representation of a program, expressions use their `ExpType`.
+const (
+	PROGRAM_NODE    = "program"
+	PRINT_STMT      = "print"
+	EXPRESSION_STMT = "expression"
+	LET_STMT        = "let"
+	BLOCK_STMT      = "block"
+	IF_STMT         = "if"
+	WHILE_STMT      = "while"
+	BRANCH_STMT     = "branch"
+	FUNCTION_STMT   = "function"
+	IMPORT_STMT     = "import"
+	THROW_STMT      = "throw"
+	TRY_STMT        = "try"
+	DEFER_STMT      = "defer"
+)
+
+// Every node is encoded as an object with a "kind" and its fields. Keys are sorted and absent children
+// are encoded as `null`, so that the same program always produces the same JSON.
+type node map[string]any
+
+func MarshalProgram(stmts []Statement) ([]byte, error) {
+	return json.Marshal(encodeProgram(stmts))
+}
+
+func MarshalProgramIndent(stmts []Statement, indent string) ([]byte, error) {
+	return json.MarshalIndent(encodeProgram(stmts), "", indent)
+}
+
+func UnmarshalProgram(data []byte) ([]Statement, error) {
+	var program struct {
+		Kind       string            `json:"kind"`
+		Statements []json.RawMessage `json:"statements"`
+	}
+	if err := json.Unmarshal(data, &program); err != nil {
+		return nil, err
+	}
+	if program.Kind != PROGRAM_NODE {
+		return nil, fmt.Errorf("expected a %q node, got %q", PROGRAM_NODE, program.Kind)
+	}
+	return decodeStmts(program.Statements)
+}
+
+func encodeProgram(stmts []Statement) node {
+	return node{"kind": PROGRAM_NODE, "statements": encodeStmts(stmts)}
+}
+
+func encodeStmts(stmts []Statement) []node {
+	nodes := make([]node, len(stmts))
+	for i, stmt := range stmts {
+		nodes[i] = encodeStmt(stmt)
+	}
+	return nodes
+}
+
+func encodeStmt(stmt Statement) node {
+	switch stmt := stmt.(type) {
+	case nil:
+		return nil
+	case *PrintStmt:
+		return node{"kind": PRINT_STMT, "expression": encodeExpr(stmt.Exp)}
+	case *ExpressionStmt:
+		return node{"kind": EXPRESSION_STMT, "expression": encodeExpr(stmt.Exp)}
+	case *LetStmt:
+		return node{"kind": LET_STMT, "name": stmt.Name, "value": encodeExpr(stmt.Value)}
+	case *BlockStmt:
+		return node{"kind": BLOCK_STMT, "statements": encodeStmts(stmt.Stmts)}
+	case *IfStmt:
+		return node{"kind": IF_STMT, "condition": encodeExpr(stmt.Condition), "then": encodeStmt(stmt.Then), "else": encodeStmt(stmt.OrElse)}
+	case *WhileStmt:
+		return node{"kind": WHILE_STMT, "condition": encodeExpr(stmt.Condition), "body": encodeStmt(stmt.Body)}
+	case *BranchStmt:
+		return node{"kind": BRANCH_STMT, "token": stmt.Token}
+	case *Function:
+		return node{"kind": FUNCTION_STMT, "name": stmt.Name, "params": stmt.Params, "body": encodeStmts(stmt.Body)}
+	case *ImportStmt:
+		if len(stmt.Names) == 0 {
+			return node{"kind": IMPORT_STMT, "token": stmt.Keyword, "path": stmt.Path, "alias": stmt.Alias, "names": nil}
+		}
+		return node{"kind": IMPORT_STMT, "token": stmt.Keyword, "path": stmt.Path, "alias": nil, "names": stmt.Names}
+	case *ThrowStmt:
+		return node{"kind": THROW_STMT, "token": stmt.Keyword, "value": encodeExpr(stmt.Value)}
+	case *TryStmt:
+		n := node{"kind": TRY_STMT, "body": encodeStmt(stmt.Body), "name": nil, "catch": nil, "finally": nil}
+		if stmt.Catch != nil {
+			n["name"], n["catch"] = stmt.Name, encodeStmt(stmt.Catch)
+		}
+		if stmt.Finally != nil {
+			n["finally"] = encodeStmt(stmt.Finally)
+		}
+		return n
+	case *DeferStmt:
+		return node{"kind": DEFER_STMT, "token": stmt.Keyword, "expression": encodeExpr(stmt.Call)}
+	}
+	panic(fmt.Sprintf("%T cannot be encoded to JSON.", stmt))
+}
+
+func encodeExpr(exp Expression) node {
+	if exp == nil {
+		return nil
+	}
+
+	n := node{"kind": exp.Type()}
+	switch exp := exp.(type) {
+	case *Literal:
+		n["value"] = exp.Value
+	case *Unary:
+		n["operator"], n["right"] = exp.Operator, encodeExpr(exp.Right)
+	case *Binary:
+		n["left"], n["operator"], n["right"] = encodeExpr(exp.Left), exp.Operator, encodeExpr(exp.Right)
+	case *Grouping:
+		n["expression"] = encodeExpr(exp.Exp)
+	case *Ternary:
+		n["condition"], n["then"], n["else"] = encodeExpr(exp.Condition), encodeExpr(exp.Then), encodeExpr(exp.OrElse)
+		n["then_operator"], n["else_operator"] = exp.ThenOperator, exp.OrElseOperator
+	case *Variable:
+		n["name"] = exp.Name
+	case *Assignment:
+		n["name"], n["value"] = exp.Name, encodeExpr(exp.Value)
+	case *Logical:
+		n["left"], n["operator"], n["right"] = encodeExpr(exp.Left), exp.Operator, encodeExpr(exp.Right)
+	case *Call:
+		args := make([]node, len(exp.Args))
+		for i, arg := range exp.Args {
+			args[i] = encodeExpr(arg)
+		}
+		n["callee"], n["paren"], n["arguments"] = encodeExpr(exp.Callee), exp.Paren, args
+	case *Get:
+		n["object"], n["name"] = encodeExpr(exp.Object), exp.Name
+	case *Set:
+		n["object"], n["name"], n["value"] = encodeExpr(exp.Object), exp.Name, encodeExpr(exp.Value)
+	default:
+		panic(fmt.Sprintf("%T cannot be encoded to JSON.", exp))
+	}
+	return n
+}
+
+// rawNode holds every field a node can have, children are decoded once the node's kind is known.
+type rawNode struct {
+	Kind         string            `json:"kind"`
+	Value        json.RawMessage   `json:"value"`
+	Operator     *token.Token      `json:"operator"`
+	Left         json.RawMessage   `json:"left"`
+	Right        json.RawMessage   `json:"right"`
+	Expression   json.RawMessage   `json:"expression"`
+	Condition    json.RawMessage   `json:"condition"`
+	Then         json.RawMessage   `json:"then"`
+	Else         json.RawMessage   `json:"else"`
+	ThenOperator *token.Token      `json:"then_operator"`
+	ElseOperator *token.Token      `json:"else_operator"`
+	Name         *token.Token      `json:"name"`
+	Callee       json.RawMessage   `json:"callee"`
+	Object       json.RawMessage   `json:"object"`
+	Paren        *token.Token      `json:"paren"`
+	Arguments    []json.RawMessage `json:"arguments"`
+	Statements   []json.RawMessage `json:"statements"`
+	Body         json.RawMessage   `json:"body"`
+	Token        *token.Token      `json:"token"`
+	Params       []token.Token     `json:"params"`
+	Path         *token.Token      `json:"path"`
+	Alias        *token.Token      `json:"alias"`
+	Names        []token.Token     `json:"names"`
+	Catch        json.RawMessage   `json:"catch"`
+	Finally      json.RawMessage   `json:"finally"`
+}
+
+func decodeStmts(raws []json.RawMessage) ([]Statement, error) {
+	stmts := make([]Statement, len(raws))
+	for i, raw := range raws {
+		stmt, err := decodeStmt(raw)
+		if err != nil {
+			return nil, err
+		}
+		stmts[i] = stmt
+	}
+	return stmts, nil
+}
+
+func decodeStmt(raw json.RawMessage) (Statement, error) {
+	n, err := decodeNode(raw)
+	if n == nil || err != nil {
+		return nil, err
+	}
+
+	switch n.Kind {
+	case PRINT_STMT, EXPRESSION_STMT:
+		exp, err := decodeRequiredExpr(n.Kind, "expression", n.Expression)
+		if err != nil {
+			return nil, err
+		}
+		if n.Kind == PRINT_STMT {
+			return NewPrintStmt(exp), nil
+		}
+		return NewExprStmt(exp), nil
+	case LET_STMT:
+		name, err := decodeToken(n.Kind, "name", n.Name)
+		if err != nil {
+			return nil, err
+		}
+		val, err := decodeExpr(n.Value)
+		return NewLetStmt(name, val), err
+	case BLOCK_STMT:
+		stmts, err := decodeStmts(n.Statements)
+		return NewBlockStmt(stmts), err
+	case IF_STMT:
+		cond, err := decodeRequiredExpr(n.Kind, "condition", n.Condition)
+		if err != nil {
+			return nil, err
+		}
+		then, err := decodeRequiredStmt(n.Kind, "then", n.Then)
+		if err != nil {
+			return nil, err
+		}
+		orElse, err := decodeStmt(n.Else)
+		return NewIfStmt(cond, then, orElse), err
+	case WHILE_STMT:
+		cond, err := decodeRequiredExpr(n.Kind, "condition", n.Condition)
+		if err != nil {
+			return nil, err
+		}
+		body, err := decodeRequiredStmt(n.Kind, "body", n.Body)
+		return NewWhileStmt(cond, body), err
+	case BRANCH_STMT:
+		tok, err := decodeToken(n.Kind, "token", n.Token)
+		return NewBranch(tok), err
+	case FUNCTION_STMT:
+		name, err := decodeToken(n.Kind, "name", n.Name)
+		if err != nil {
+			return nil, err
+		}
+		params := n.Params
+		if params == nil {
+			params = []token.Token{}
+		}
+		var rawBody []json.RawMessage
+		if err := json.Unmarshal(n.Body, &rawBody); err != nil {
+			return nil, fmt.Errorf("invalid %q node: %v", n.Kind, err)
+		}
+		body, err := decodeStmts(rawBody)
+		return NewFunction(name, params, body), err
+	case IMPORT_STMT:
+		keyword, err := decodeToken(n.Kind, "token", n.Token)
+		if err != nil {
+			return nil, err
+		}
+		path, err := decodeToken(n.Kind, "path", n.Path)
+		if err != nil {
+			return nil, err
+		}
+		if len(n.Names) > 0 {
+			return NewImportStmt(keyword, path, token.Token{}, n.Names), nil
+		}
+		alias, err := decodeToken(n.Kind, "alias", n.Alias)
+		return NewImportStmt(keyword, path, alias, nil), err
+	case THROW_STMT:
+		keyword, err := decodeToken(n.Kind, "token", n.Token)
+		if err != nil {
+			return nil, err
+		}
+		val, err := decodeRequiredExpr(n.Kind, "value", n.Value)
+		return NewThrowStmt(keyword, val), err
+	case TRY_STMT:
+		body, err := decodeBlock(n.Kind, "body", n.Body, true)
+		if err != nil {
+			return nil, err
+		}
+		catch, err := decodeBlock(n.Kind, "catch", n.Catch, false)
+		if err != nil {
+			return nil, err
+		}
+		var name token.Token
+		if catch != nil {
+			if name, err = decodeToken(n.Kind, "name", n.Name); err != nil {
+				return nil, err
+			}
+		}
+		finally, err := decodeBlock(n.Kind, "finally", n.Finally, false)
+		if err == nil && catch == nil && finally == nil {
+			err = fmt.Errorf("invalid %q node: missing %q or %q", n.Kind, "catch", "finally")
+		}
+		return NewTryStmt(body, name, catch, finally), err
+	case DEFER_STMT:
+		keyword, err := decodeToken(n.Kind, "token", n.Token)
+		if err != nil {
+			return nil, err
+		}
+		exp, err := decodeRequiredExpr(n.Kind, "expression", n.Expression)
+		if err != nil {
+			return nil, err
+		}
+		call, isCall := exp.(*Call)
+		if !isCall {
+			return nil, fmt.Errorf("invalid %q node: %q must be a call", n.Kind, "expression")
+		}
+		return NewDeferStmt(keyword, call), nil
+	}
+	return nil, fmt.Errorf("unknown statement kind %q", n.Kind)
+}
+
+func decodeExpr(raw json.RawMessage) (Expression, error) {
+	n, err := decodeNode(raw)
+	if n == nil || err != nil {
+		return nil, err
+	}
+
+	switch ExpType(n.Kind) {
+	case LITERAL_EXP:
+		var val any
+		if len(n.Value) > 0 {
+			if err := json.Unmarshal(n.Value, &val); err != nil {
+				return nil, err
+			}
+		}
+		return NewLiteralExpression(val), nil
+	case UNARY_EXP:
+		op, err := decodeToken(n.Kind, "operator", n.Operator)
+		if err != nil {
+			return nil, err
+		}
+		right, err := decodeRequiredExpr(n.Kind, "right", n.Right)
+		return NewUnaryExpression(op, right), err
+	case BINARY_EXP, LOGICAL_AND_EXP, LOGICAL_OR_EXP:
+		left, err := decodeRequiredExpr(n.Kind, "left", n.Left)
+		if err != nil {
+			return nil, err
+		}
+		op, err := decodeToken(n.Kind, "operator", n.Operator)
+		if err != nil {
+			return nil, err
+		}
+		right, err := decodeRequiredExpr(n.Kind, "right", n.Right)
+		if err != nil {
+			return nil, err
+		}
+		if ExpType(n.Kind) == BINARY_EXP {
+			return NewBinaryExpression(left, op, right), nil
+		}
+		if op.Type != token.AND && op.Type != token.OR {
+			return nil, fmt.Errorf("invalid %q node: operator %q is not a logical operator", n.Kind, op.Lexeme)
+		}
+		return NewLogical(left, op, right), nil
+	case GROUP_EXP:
+		exp, err := decodeRequiredExpr(n.Kind, "expression", n.Expression)
+		return NewGroupingExp(exp), err
+	case TERNARY_EXP:
+		cond, err := decodeRequiredExpr(n.Kind, "condition", n.Condition)
+		if err != nil {
+			return nil, err
+		}
+		thenOp, err := decodeToken(n.Kind, "then_operator", n.ThenOperator)
+		if err != nil {
+			return nil, err
+		}
+		then, err := decodeRequiredExpr(n.Kind, "then", n.Then)
+		if err != nil {
+			return nil, err
+		}
+		elseOp, err := decodeToken(n.Kind, "else_operator", n.ElseOperator)
+		if err != nil {
+			return nil, err
+		}
+		orElse, err := decodeRequiredExpr(n.Kind, "else", n.Else)
+		return NewTernaryConditional(cond, thenOp, then, elseOp, orElse), err
+	case VARIABLE_EXP:
+		name, err := decodeToken(n.Kind, "name", n.Name)
+		return NewVariable(name), err
+	case ASSIGNMENT_EXP:
+		name, err := decodeToken(n.Kind, "name", n.Name)
+		if err != nil {
+			return nil, err
+		}
+		val, err := decodeRequiredExpr(n.Kind, "value", n.Value)
+		return NewAssignment(name, val), err
+	case CALL_EXP:
+		callee, err := decodeRequiredExpr(n.Kind, "callee", n.Callee)
+		if err != nil {
+			return nil, err
+		}
+		paren, err := decodeToken(n.Kind, "paren", n.Paren)
+		if err != nil {
+			return nil, err
+		}
+		args := make([]Expression, len(n.Arguments))
+		for i, raw := range n.Arguments {
+			if args[i], err = decodeRequiredExpr(n.Kind, "arguments", raw); err != nil {
+				return nil, err
+			}
+		}
+		return NewCall(callee, paren, args), nil
+	case GET_EXP, SET_EXP:
+		object, err := decodeRequiredExpr(n.Kind, "object", n.Object)
+		if err != nil {
+			return nil, err
+		}
+		name, err := decodeToken(n.Kind, "name", n.Name)
+		if err != nil {
+			return nil, err
+		}
+		if ExpType(n.Kind) == GET_EXP {
+			return NewGet(object, name), nil
+		}
+		val, err := decodeRequiredExpr(n.Kind, "value", n.Value)
+		return NewSet(object, name, val), err
+	}
+	return nil, fmt.Errorf("unknown expression kind %q", n.Kind)
+}
+
+func decodeNode(raw json.RawMessage) (*rawNode, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var n rawNode
+	if err := json.Unmarshal(raw, &n); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+func decodeRequiredExpr(kind string, field string, raw json.RawMessage) (Expression, error) {
+	exp, err := decodeExpr(raw)
+	if err == nil && exp == nil {
+		err = fmt.Errorf("invalid %q node: missing %q", kind, field)
+	}
+	return exp, err
+}
+
+func decodeRequiredStmt(kind string, field string, raw json.RawMessage) (Statement, error) {
+	stmt, err := decodeStmt(raw)
+	if err == nil && stmt == nil {
+		err = fmt.Errorf("invalid %q node: missing %q", kind, field)
+	}
+	return stmt, err
+}
+
+// Decodes a child that must be a block, nil when it is optional and absent.
+func decodeBlock(kind string, field string, raw json.RawMessage, required bool) (*BlockStmt, error) {
+	stmt, err := decodeStmt(raw)
+	if err != nil {
+		return nil, err
+	} else if stmt == nil && required {
+		return nil, fmt.Errorf("invalid %q node: missing %q", kind, field)
+	} else if stmt == nil {
+		return nil, nil
+	}
+	block, isBlock := stmt.(*BlockStmt)
+	if !isBlock {
+		return nil, fmt.Errorf("invalid %q node: %q must be a block", kind, field)
+	}
+	return block, nil
+}
+
+func decodeToken(kind string, field string, tok *token.Token) (token.Token, error) {
+	if tok == nil {
+		return token.Token{}, fmt.Errorf("invalid %q node: missing %q", kind, field)
+	}
+	return *tok, nil
+}
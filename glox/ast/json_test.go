@@ -0,0 +1,132 @@
+package ast
+
+import (
+	"glox/token"
+	"strings"
+	"testing"
+)
+
+func TestMarshalProgram(t *testing.T) {
+	program := []Statement{
+		NewLetStmt(
+			token.Token{Type: token.IDENTIFIER, Lexeme: "x", Line: 1},
+			NewBinaryExpression(
+				NewLiteralExpression(1.0),
+				token.Token{Type: token.PLUS, Lexeme: "+", Line: 1},
+				NewLiteralExpression(2.0),
+			),
+		),
+	}
+
+	got, err := MarshalProgram(program)
+	if err != nil {
+		t.Fatalf("failed to marshal program. got error='%s'", err.Error())
+	}
+	want := `{"kind":"program","statements":[{"kind":"let","name":{"type":"IDENT","lexeme":"x","line":1},"value":{"kind":"binary","left":{"kind":"literal","value":1},"operator":{"type":"PLUS","lexeme":"+","line":1},"right":{"kind":"literal","value":2}}}]}`
+	if string(got) != want {
+		t.Fatalf("wrong JSON for program.\nwant=%s\ngot=%s", want, got)
+	}
+}
+
+func TestUnmarshalProgram(t *testing.T) {
+	ident := func(name string) token.Token {
+		return token.Token{Type: token.IDENTIFIER, Lexeme: name, Line: 2}
+	}
+	program := []Statement{
+		NewFunction(ident("greet"), []token.Token{ident("name")}, []Statement{
+			NewPrintStmt(NewBinaryExpression(
+				NewLiteralExpression("hello "),
+				token.Token{Type: token.PLUS, Lexeme: "+", Line: 2},
+				NewVariable(ident("name")),
+			)),
+		}),
+		NewLetStmt(ident("empty"), nil),
+		NewIfStmt(
+			NewLogical(NewLiteralExpression(true), token.Token{Type: token.AND, Lexeme: "and", Line: 3}, NewUnaryExpression(token.Token{Type: token.BANG, Lexeme: "!", Line: 3}, NewLiteralExpression(nil))),
+			NewBlockStmt([]Statement{
+				NewExprStmt(NewCall(NewVariable(ident("greet")), token.Token{Type: token.R_PAREN, Lexeme: ")", Line: 3}, []Expression{NewLiteralExpression("anya")})),
+				NewExprStmt(NewSet(NewGet(NewVariable(ident("order")), ident("customer")), ident("name"), NewLiteralExpression("bo"))),
+			}),
+			nil,
+		),
+		NewWhileStmt(
+			NewGroupingExp(NewTernaryConditional(
+				NewLiteralExpression(false),
+				token.Token{Type: token.QUESTION_MARK, Lexeme: "?", Line: 4},
+				NewLiteralExpression(1.0),
+				token.Token{Type: token.COLON, Lexeme: ":", Line: 4},
+				NewLiteralExpression(2.0),
+			)),
+			NewBlockStmt([]Statement{
+				NewExprStmt(NewAssignment(ident("empty"), NewLiteralExpression(3.5))),
+				NewBranch(token.Token{
+					Type: token.BREAK, Lexeme: "break", Line: 5,
+					Leading: []token.Trivia{{Type: token.SLASH_SLASH, Lexeme: "// stop", Line: 4}},
+				}),
+			}),
+		),
+		NewImportStmt(token.Token{Type: token.IMPORT, Lexeme: "import", Line: 6}, token.Token{Type: token.STRING, Lexeme: `"lib/math.lox"`, Literal: "lib/math.lox", Line: 6}, ident("m"), nil),
+		NewTryStmt(
+			NewBlockStmt([]Statement{NewThrowStmt(token.Token{Type: token.THROW, Lexeme: "throw", Line: 8}, NewLiteralExpression("oops"))}),
+			ident("e"),
+			NewBlockStmt([]Statement{NewPrintStmt(NewGet(NewVariable(ident("e")), ident("message")))}),
+			NewBlockStmt([]Statement{}),
+		),
+		NewFunction(ident("close"), []token.Token{}, []Statement{
+			NewDeferStmt(token.Token{Type: token.DEFER, Lexeme: "defer", Line: 9}, NewCall(NewVariable(ident("log")), token.Token{Type: token.R_PAREN, Lexeme: ")", Line: 9}, []Expression{NewLiteralExpression("closed")})),
+		}),
+		NewImportStmt(token.Token{Type: token.IDENTIFIER, Lexeme: "from", Line: 7}, token.Token{Type: token.STRING, Lexeme: `"lib/math.lox"`, Literal: "lib/math.lox", Line: 7}, token.Token{}, []token.Token{ident("min"), ident("max")}),
+	}
+
+	data, err := MarshalProgramIndent(program, "  ")
+	if err != nil {
+		t.Fatalf("failed to marshal program. got error='%s'", err.Error())
+	}
+	decoded, err := UnmarshalProgram(data)
+	if err != nil {
+		t.Fatalf("failed to unmarshal program. got error='%s'", err.Error())
+	}
+	again, err := MarshalProgramIndent(decoded, "  ")
+	if err != nil {
+		t.Fatalf("failed to marshal decoded program. got error='%s'", err.Error())
+	}
+	if string(again) != string(data) {
+		t.Fatalf("decoded program does not match the original.\nwant=%s\ngot=%s", data, again)
+	}
+
+	let, isOk := decoded[1].(*LetStmt)
+	if !isOk || let.Name.Lexeme != "empty" || let.Value != nil {
+		t.Fatalf("wrong decoded let statement. got=%+v", decoded[1])
+	}
+	branch := decoded[3].(*WhileStmt).Body.(*BlockStmt).Stmts[1].(*BranchStmt)
+	if len(branch.Token.Leading) != 1 || branch.Token.Leading[0].Lexeme != "// stop" {
+		t.Fatalf("token trivia must survive a round trip. got=%v", branch.Token.Leading)
+	}
+	if from := decoded[7].(*ImportStmt); from.Alias.Lexeme != "" || len(from.Names) != 2 {
+		t.Fatalf("wrong decoded import statement. got=%+v", from)
+	}
+}
+
+func TestUnmarshalProgramInvalid(t *testing.T) {
+	tests := []struct {
+		json string
+		err  string
+	}{
+		{json: `[]`, err: "cannot unmarshal"},
+		{json: `{"kind":"block"}`, err: `expected a "program" node`},
+		{json: `{"kind":"program","statements":[{"kind":"loop"}]}`, err: `unknown statement kind "loop"`},
+		{json: `{"kind":"program","statements":[{"kind":"print","expression":{"kind":"unknown"}}]}`, err: `unknown expression kind "unknown"`},
+		{json: `{"kind":"program","statements":[{"kind":"print"}]}`, err: `missing "expression"`},
+		{json: `{"kind":"program","statements":[{"kind":"expression","expression":{"kind":"variable"}}]}`, err: `missing "name"`},
+		{json: `{"kind":"program","statements":[{"kind":"try","body":{"kind":"block","statements":[]}}]}`, err: `missing "catch" or "finally"`},
+		{json: `{"kind":"program","statements":[{"kind":"try","body":{"kind":"print","expression":{"kind":"literal","value":1}},"finally":{"kind":"block","statements":[]}}]}`, err: `"body" must be a block`},
+		{json: `{"kind":"program","statements":[{"kind":"defer","token":{"type":"DEFER","lexeme":"defer","line":1},"expression":{"kind":"literal","value":1}}]}`, err: `"expression" must be a call`},
+	}
+
+	for _, test := range tests {
+		_, err := UnmarshalProgram([]byte(test.json))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Fatalf("%s -> wrong error. want contains=%q got=%v", test.json, test.err, err)
+		}
+	}
+}
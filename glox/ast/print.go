@@ -53,3 +53,11 @@ func (p *printer) VisitLogical(exp *Logical) any {
 func (p *printer) VisitCall(exp *Call) any {
 	return exp.String()
 }
+
+func (p *printer) VisitGet(exp *Get) any {
+	return exp.String()
+}
+
+func (p *printer) VisitSet(exp *Set) any {
+	return exp.String()
+}
@@ -13,6 +13,10 @@ type StmtVisitor interface {
 	VisitWhile(*WhileStmt) any
 	VisitBranch(*BranchStmt) any
 	VisitFunction(*Function) any
+	VisitImport(*ImportStmt) any
+	VisitThrow(*ThrowStmt) any
+	VisitTry(*TryStmt) any
+	VisitDefer(*DeferStmt) any
 }
 
 type Statement interface {
@@ -24,8 +28,9 @@ type PrintStmt struct {
 }
 
 type LetStmt struct {
-	Name  token.Token
-	Value Expression
+	Name    token.Token
+	Value   Expression
+	Binding Binding // Filled in by the resolver.
 }
 
 func NewLetStmt(name token.Token, value Expression) *LetStmt {
@@ -57,7 +62,8 @@ func (stmt *ExpressionStmt) Accept(v StmtVisitor) any {
 }
 
 type BlockStmt struct {
-	Stmts []Statement
+	Stmts  []Statement
+	Locals int // Number of slots needed by the block's environment, filled in by the resolver.
 }
 
 func NewBlockStmt(stmts []Statement) *BlockStmt {
@@ -109,9 +115,11 @@ func (b *BranchStmt) Accept(v StmtVisitor) any {
 }
 
 type Function struct {
-	Name   token.Token
-	Params []token.Token
-	Body   []Statement
+	Name    token.Token
+	Params  []token.Token
+	Body    []Statement
+	Binding Binding // Filled in by the resolver.
+	Locals  int     // Number of slots needed by a call's environment (parameters included), filled in by the resolver.
 }
 
 func NewFunction(name token.Token, params []token.Token, body []Statement) *Function {
@@ -121,3 +129,73 @@ func NewFunction(name token.Token, params []token.Token, body []Statement) *Func
 func (fn *Function) Accept(v StmtVisitor) any {
 	return v.VisitFunction(fn)
 }
+
+// ImportStmt is either `import "path" as name;` or `from "path" import a, b;`, Names is empty in the first
+// form.
+type ImportStmt struct {
+	Keyword  token.Token // `import` or `from`, where errors raised while loading the module are reported.
+	Path     token.Token
+	Alias    token.Token
+	Names    []token.Token
+	Bindings []Binding // Of the alias or of each name, filled in by the resolver.
+}
+
+func NewImportStmt(keyword token.Token, path token.Token, alias token.Token, names []token.Token) *ImportStmt {
+	return &ImportStmt{Keyword: keyword, Path: path, Alias: alias, Names: names}
+}
+
+// Declared returns the names the statement defines.
+func (stmt *ImportStmt) Declared() []token.Token {
+	if len(stmt.Names) == 0 {
+		return []token.Token{stmt.Alias}
+	}
+	return stmt.Names
+}
+
+func (stmt *ImportStmt) Accept(v StmtVisitor) any {
+	return v.VisitImport(stmt)
+}
+
+type ThrowStmt struct {
+	Keyword token.Token
+	Value   Expression
+}
+
+func NewThrowStmt(keyword token.Token, val Expression) *ThrowStmt {
+	return &ThrowStmt{Keyword: keyword, Value: val}
+}
+
+func (stmt *ThrowStmt) Accept(v StmtVisitor) any {
+	return v.VisitThrow(stmt)
+}
+
+// TryStmt is `try { } catch (name) { } finally { }`, either clause can be left out but not both. Catch and
+// Finally are nil when they are.
+type TryStmt struct {
+	Body    *BlockStmt
+	Name    token.Token // Of the caught exception, it takes the first slot of the catch block's environment.
+	Catch   *BlockStmt
+	Finally *BlockStmt
+}
+
+func NewTryStmt(body *BlockStmt, name token.Token, catch *BlockStmt, finally *BlockStmt) *TryStmt {
+	return &TryStmt{Body: body, Name: name, Catch: catch, Finally: finally}
+}
+
+func (stmt *TryStmt) Accept(v StmtVisitor) any {
+	return v.VisitTry(stmt)
+}
+
+// DeferStmt is `defer call;` in a function body, the call runs when the function returns.
+type DeferStmt struct {
+	Keyword token.Token
+	Call    *Call
+}
+
+func NewDeferStmt(keyword token.Token, call *Call) *DeferStmt {
+	return &DeferStmt{Keyword: keyword, Call: call}
+}
+
+func (stmt *DeferStmt) Accept(v StmtVisitor) any {
+	return v.VisitDefer(stmt)
+}
@@ -0,0 +1,149 @@
+package capability
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Kind is something a program can do outside the interpreter, the names match the `--allow-*` flags.
+type Kind string
+
+const (
+	READ  Kind = "read"  // Read files, the resources are paths.
+	WRITE Kind = "write" // Write files, the resources are paths.
+	ENV   Kind = "env"   // Read environment variables, the resources are their names.
+	RUN   Kind = "run"   // Run programs, the resources are their names.
+)
+
+// Wrapped by the errors of `Set.Check`.
+var ErrDenied = errors.New("permission denied")
+
+// Set holds the capabilities granted to a program. The zero value denies everything.
+type Set struct {
+	Read  Scope
+	Write Scope
+	Env   Scope
+	Run   Scope
+}
+
+// All grants every capability for every resource.
+func All() Set {
+	return Set{Read: Scope{All: true}, Write: Scope{All: true}, Env: Scope{All: true}, Run: Scope{All: true}}
+}
+
+// Check returns an error wrapping ErrDenied unless the capability is granted for the resource.
+func (s Set) Check(kind Kind, resource string) error {
+	scope := s.scope(kind)
+	if scope != nil && scope.allows(kind, resource) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s access to %q, it must be allowed with --allow-%s.", ErrDenied, kind, resource, kind)
+}
+
+func (s *Set) scope(kind Kind) *Scope {
+	switch kind {
+	case READ:
+		return &s.Read
+	case WRITE:
+		return &s.Write
+	case ENV:
+		return &s.Env
+	case RUN:
+		return &s.Run
+	}
+	return nil
+}
+
+// Scope grants a capability for every resource or only the listed ones. A path grants access to the files
+// below it. It is a flag.Value: `--allow-read` grants everything, `--allow-read=a,b` grants `a` and `b`.
+type Scope struct {
+	All  bool
+	Only []string
+}
+
+func (s *Scope) allows(kind Kind, resource string) bool {
+	if s.All {
+		return true
+	}
+	for _, allowed := range s.Only {
+		if kind == READ || kind == WRITE {
+			if within(resource, allowed) {
+				return true
+			}
+		} else if resource == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// Tells whether path is dir or a file below it. Paths are compared once made absolute and their symbolic
+// links resolved, so that a link cannot lead outside of dir.
+func within(path string, dir string) bool {
+	path, err := resolve(path)
+	if err != nil {
+		return false
+	}
+	if dir, err = resolve(dir); err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Returns the absolute path with its symbolic links resolved. A file that does not exist yet, e.g. one about
+// to be written, is resolved in its closest existing parent directory.
+func resolve(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err == nil {
+		return resolved, nil
+	}
+	// A link to a missing file exists, writing it would create its target wherever that is.
+	if _, statErr := os.Lstat(path); !errors.Is(statErr, fs.ErrNotExist) {
+		return "", err
+	}
+	parent := filepath.Dir(path)
+	if parent == path {
+		return "", err
+	}
+	if parent, err = resolve(parent); err != nil {
+		return "", err
+	}
+	return filepath.Join(parent, filepath.Base(path)), nil
+}
+
+func (s *Scope) String() string {
+	if s == nil {
+		return ""
+	}
+	if s.All {
+		return "true"
+	}
+	return strings.Join(s.Only, ",")
+}
+
+func (s *Scope) Set(value string) error {
+	if value == "true" || value == "false" {
+		s.All = value == "true"
+		return nil
+	}
+	for _, resource := range strings.Split(value, ",") {
+		if resource = strings.TrimSpace(resource); resource != "" {
+			s.Only = append(s.Only, resource)
+		}
+	}
+	return nil
+}
+
+// Lets the flag be given without a value.
+func (s *Scope) IsBoolFlag() bool {
+	return true
+}
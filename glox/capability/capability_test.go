@@ -0,0 +1,117 @@
+package capability
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	set := Set{
+		Read: Scope{Only: []string{dir}},
+		Env:  Scope{Only: []string{"HOME"}},
+		Run:  Scope{All: true},
+	}
+
+	tests := []struct {
+		kind     Kind
+		resource string
+		want     bool
+	}{
+		{READ, dir, true},
+		{READ, filepath.Join(dir, "sub", "file.lox"), true},
+		{READ, filepath.Join(dir, "..", "other.lox"), false},
+		{READ, dir + "-other", false},
+		{WRITE, filepath.Join(dir, "file.lox"), false},
+		{ENV, "HOME", true},
+		{ENV, "PATH", false},
+		{RUN, "git", true},
+		{Kind("network"), "example.com", false},
+	}
+	for _, test := range tests {
+		err := set.Check(test.kind, test.resource)
+		if test.want && err != nil {
+			t.Fatalf("%s %q must be allowed. got=%v", test.kind, test.resource, err)
+		}
+		if !test.want && !errors.Is(err, ErrDenied) {
+			t.Fatalf("%s %q must be denied. got=%v", test.kind, test.resource, err)
+		}
+	}
+
+	if err := (Set{}).Check(READ, dir); err == nil {
+		t.Fatalf("the zero set must deny everything")
+	}
+	if err := All().Check(WRITE, "/"); err != nil {
+		t.Fatalf("All must allow everything. got=%v", err)
+	}
+}
+
+func TestCheckSymlinks(t *testing.T) {
+	dir, outside := t.TempDir(), t.TempDir()
+	secret := filepath.Join(outside, "secret.txt")
+	os.WriteFile(secret, []byte("secret"), 0644)
+	os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	links := map[string]string{
+		"secret.txt":  secret,
+		"outside":     outside,
+		"dangling":    filepath.Join(outside, "new.txt"),
+		"sub/up":      dir,
+		"sub/sibling": filepath.Join(dir, "sub"),
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, filepath.Join(dir, link)); err != nil {
+			t.Skipf("symbolic links cannot be created. got=%v", err)
+		}
+	}
+	set := Set{Read: Scope{Only: []string{dir}}, Write: Scope{Only: []string{dir}}}
+
+	tests := []struct {
+		kind     Kind
+		resource string
+		want     bool
+	}{
+		{READ, filepath.Join(dir, "secret.txt"), false},
+		{READ, filepath.Join(dir, "outside", "secret.txt"), false},
+		{WRITE, filepath.Join(dir, "outside", "new.txt"), false},
+		{WRITE, filepath.Join(dir, "outside", "sub", "new.txt"), false},
+		{WRITE, filepath.Join(dir, "dangling"), false},
+		{READ, filepath.Join(dir, "sub", "up", "file.lox"), true},
+		{WRITE, filepath.Join(dir, "sub", "sibling", "new.txt"), true},
+		{WRITE, filepath.Join(dir, "new", "file.txt"), true},
+	}
+	for _, test := range tests {
+		err := set.Check(test.kind, test.resource)
+		if test.want && err != nil {
+			t.Fatalf("%s %q must be allowed. got=%v", test.kind, test.resource, err)
+		}
+		if !test.want && !errors.Is(err, ErrDenied) {
+			t.Fatalf("%s %q must be denied. got=%v", test.kind, test.resource, err)
+		}
+	}
+}
+
+func TestScopeFlag(t *testing.T) {
+	var set Set
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.Var(&set.Read, "allow-read", "")
+	flags.Var(&set.Env, "allow-env", "")
+	flags.Var(&set.Run, "allow-run", "")
+
+	if err := flags.Parse([]string{"--allow-read", "--allow-env=HOME,USER", "script.lox"}); err != nil {
+		t.Fatalf("unexpected error. got=%v", err)
+	}
+	if !set.Read.All || set.Env.All || set.Run.All {
+		t.Fatalf("wrong scopes. got=%+v", set)
+	}
+	if got := set.Env.String(); got != "HOME,USER" {
+		t.Fatalf("wrong env scope. got=%q", got)
+	}
+	if flags.Arg(0) != "script.lox" {
+		t.Fatalf("a boolean flag must not take the next argument. got=%v", flags.Args())
+	}
+}
@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"glox/lox"
+	"glox/source"
+	"io"
+	"os"
+)
+
+// Version of glox, set when building a release with `-ldflags "-X glox/cli.Version=v0.0.1"`.
+var Version = "dev"
+
+// Exit statuses, see sysexits(3).
+const (
+	EX_USAGE    = 64 // The command was invoked with invalid arguments.
+	EX_DATAERR  = 65 // The script has syntax errors.
+	EX_SOFTWARE = 70 // The script failed while running.
+	EX_IOERR    = 74 // The script could not be read or written.
+)
+
+type command struct {
+	name    string
+	usage   string // Arguments of the command, e.g. `[--json] script`.
+	summary string
+	run     func(c *CLI, args []string) int
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"run", "[-O] [--allow-*] [-e code | script | -] [args...]", "Run a script, inline code or the program read from stdin.", (*CLI).run},
+		{"repl", "[-O] [--allow-*] [-prompt text]", "Start an interactive session.", (*CLI).repl},
+		{"check", "[script | -]...", "Report syntax errors without running anything.", (*CLI).check},
+		{"fmt", "[--check | --write] [script | -]...", "Format scripts, stdin when none is given.", (*CLI).fmt},
+		{"tokens", "[--json] script | -", "Print the tokens of a script.", (*CLI).tokens},
+		{"ast", "--json script | -", "Print the syntax tree of a script.", (*CLI).ast},
+		{"version", "", "Print the version of glox.", (*CLI).version},
+		{"help", "[command]", "Print the usage of glox or of a command.", (*CLI).help},
+	}
+}
+
+// CLI runs glox commands against the given streams instead of the process' ones, so that it can be tested.
+type CLI struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// Main runs the command described by args (without the program name) and returns the exit status.
+//
+// Without a command glox starts the REPL, and `glox script.lox` or `glox -e 'code'` are shorthands for
+// `glox run`. Arguments following the script are passed to it.
+func Main(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	c := &CLI{stdin: stdin, stdout: stdout, stderr: stderr}
+	if len(args) == 0 {
+		return c.repl(args)
+	}
+
+	switch args[0] {
+	case "-h", "-help", "--help":
+		return c.help(args[1:])
+	case "--version":
+		return c.version(args[1:])
+	}
+	if cmd, found := lookup(args[0]); found {
+		return cmd.run(c, args[1:])
+	}
+	return c.run(args)
+}
+
+func lookup(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func (c *CLI) usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: glox <command> [arguments]\n")
+	fmt.Fprintf(w, "       glox [-O] [-e code | script | -] [args...]\n\n")
+	fmt.Fprintf(w, "Commands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(w, "\nRun `glox help <command>` for more information about a command.\n")
+}
+
+// Returns the flag set of a command. Its usage is only printed by `parse`, on the stream matching the
+// reason it is printed.
+func (c *CLI) flags(name string) *flag.FlagSet {
+	cmd, _ := lookup(name)
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: glox %s %s\n\n%s\n", cmd.name, cmd.usage, cmd.summary)
+		hasFlags := false
+		flags.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintf(flags.Output(), "\nFlags:\n")
+			flags.PrintDefaults()
+		}
+	}
+	return flags
+}
+
+// Parses the arguments of a command. When the command must not run, because help was asked for or because
+// the arguments are invalid, it returns false along with the exit status.
+func (c *CLI) parse(flags *flag.FlagSet, args []string) (int, bool) {
+	flags.SetOutput(io.Discard)
+	err := flags.Parse(args)
+	if err == flag.ErrHelp {
+		flags.SetOutput(c.stdout)
+		flags.Usage()
+		return 0, false
+	} else if err != nil {
+		return c.usageError(flags, err.Error()), false
+	}
+	return 0, true
+}
+
+func (c *CLI) usageError(flags *flag.FlagSet, msg string) int {
+	fmt.Fprintf(c.stderr, "%s\n", msg)
+	flags.SetOutput(c.stderr)
+	flags.Usage()
+	return EX_USAGE
+}
+
+// Returns the exit status for an error returned by a runner, reporting it first unless the runner already did.
+func (c *CLI) status(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, lox.ErrSyntax):
+		return EX_DATAERR
+	case errors.Is(err, lox.ErrRuntime):
+		return EX_SOFTWARE
+	}
+	fmt.Fprintf(c.stderr, "%v\n", err)
+	return EX_IOERR
+}
+
+// Reads a script as it is written, `-` reads stdin. It also returns the name to report the script with.
+func (c *CLI) read(path string) (string, string, error) {
+	if path == "-" {
+		src, err := io.ReadAll(c.stdin)
+		return string(src), source.STDIN, err
+	}
+	src, err := os.ReadFile(path)
+	return string(src), path, err
+}
+
+// Reads a script to run or analyse it.
+func (c *CLI) load(path string) (*source.Source, error) {
+	if path == "-" {
+		return source.Read(source.STDIN, c.stdin)
+	}
+	return source.Load(path)
+}
@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"bytes"
+	"glox/source"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommands(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "greet.lox")
+	if err := os.WriteFile(script, []byte(`print "hello " + args(0) + " " + argc();`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	shebang := filepath.Join(dir, "shebang.lox")
+	if err := os.WriteFile(shebang, []byte(source.BOM+"#!/usr/bin/env glox\nprint 1;\n  print nope;"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	invalid := filepath.Join(dir, "invalid.lox")
+	if err := os.WriteFile(invalid, []byte(`print (1 + 2;`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	module := filepath.Join(dir, "module.lox")
+	if err := os.WriteFile(module, []byte(`let answer = 42;`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	unformatted := filepath.Join(dir, "unformatted.lox")
+	if err := os.WriteFile(unformatted, []byte(source.BOM+"print   1;"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	unparsable := filepath.Join(dir, "unparsable.lox")
+	if err := os.WriteFile(unparsable, []byte("#!/usr/bin/env glox\nprint 1;\nlet = 2;"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args       []string
+		stdin      string
+		wantStatus int
+		wantOut    string
+		wantErr    string
+	}{
+		{[]string{script, "anya", "--loud"}, "", 0, "hello anya 2\n", ""},
+		{[]string{"run", script, "anya"}, "", 0, "hello anya 1\n", ""},
+		{[]string{"-e", "print 1 + argc();", "a", "b"}, "", 0, "3\n", ""},
+		{[]string{"run", "-O", "-e", "print args(0);", "x"}, "", 0, "x\n", ""},
+		{[]string{"run", "-", "from-stdin"}, "print args(0);", 0, "from-stdin\n", ""},
+		{[]string{"-e", "args(1);"}, "", EX_SOFTWARE, "", "argument index 1 out of range"},
+		{[]string{"-e", "print 1; { print 1/0; } print 2;"}, "", EX_SOFTWARE, "1\n", "division by zero"},
+		{[]string{"-e", "print (1;"}, "", EX_DATAERR, "", "expected ')' after expression"},
+		{[]string{invalid}, "", EX_DATAERR, "", invalid + ":1:13: unhandled exception"},
+		{[]string{shebang}, "", EX_SOFTWARE, "1\n", shebang + ":3:9: unhandled exception"},
+		{[]string{"-e", "print nope;"}, "", EX_SOFTWARE, "", source.EVAL + ":1:7:"},
+		{[]string{"run", "-"}, "\n\"unterminated", EX_DATAERR, "", source.STDIN + ":2:1:"},
+		{[]string{filepath.Join(dir, "missing.lox")}, "", EX_IOERR, "", "missing.lox"},
+		{[]string{"run"}, "", EX_USAGE, "", "no script to run"},
+		{[]string{"run", "--nope"}, "", EX_USAGE, "", "flag provided but not defined: -nope"},
+		{[]string{"-e", "print readFile(args(0));", script}, "", EX_SOFTWARE, "", "permission denied: read access"},
+		{[]string{"--allow-read=" + dir, "-e", "print readFile(args(0));", script}, "", 0, "hello", ""},
+		{[]string{"-e", `import "` + module + `" as m;`}, "", EX_SOFTWARE, "", "permission denied: read access"},
+		{[]string{"--allow-read=" + dir, "-e", `import "` + module + `" as m; print m.answer;`}, "", 0, "42\n", ""},
+		{[]string{"run", "--allow-env=GLOX_UNSET", "-e", "print getenv(\"GLOX_UNSET\") == nil;"}, "", 0, "true\n", ""},
+		{[]string{"repl", "-prompt", "lox> "}, "let x = 2;\nprint x * 3;", 0, "6\n", ""},
+		{[]string{"check", script}, "", 0, "", ""},
+		{[]string{"check", script, invalid}, "", EX_DATAERR, "", invalid + ":1:13:"},
+		{[]string{"check", "-"}, "let x = ;", EX_DATAERR, "", source.STDIN + ":1:9:"},
+		{[]string{"fmt", "-"}, "print   1;", 0, "print 1;\n", ""},
+		{[]string{"fmt", unformatted}, "", 0, "print 1;\n", ""},
+		{[]string{"fmt", "--check", unformatted}, "", 1, unformatted + "\n", ""},
+		{[]string{"fmt", shebang}, "", 0, "#!/usr/bin/env glox\nprint 1;\nprint nope;\n", ""},
+		{[]string{"fmt", unparsable}, "", EX_DATAERR, "", unparsable + ":3:5: unhandled exception"},
+		{[]string{"fmt", "-"}, "print (1;", EX_DATAERR, "", source.STDIN + ":1:"},
+		{[]string{"fmt", "--check", "--write"}, "", EX_USAGE, "", "cannot be used together"},
+		{[]string{"tokens", "-"}, "nil", 0, "NIL", ""},
+		{[]string{"ast", "--json", "-"}, "print 1;", 0, `"kind": "print"`, ""},
+		{[]string{"version"}, "", 0, "glox " + Version + "\n", ""},
+		{[]string{"--version"}, "", 0, "glox " + Version + "\n", ""},
+		{[]string{"--help"}, "", 0, "Usage: glox <command>", ""},
+		{[]string{"help", "tokens"}, "", 0, "Usage: glox tokens [--json] script | -", ""},
+		{[]string{"fmt", "-h"}, "", 0, "-write", ""},
+		{[]string{"help", "nope"}, "", EX_USAGE, "", `unknown command "nope"`},
+	}
+
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+		status := Main(test.args, strings.NewReader(test.stdin), &stdout, &stderr)
+
+		if status != test.wantStatus {
+			t.Fatalf("%v: wrong exit status. want=%d got=%d stderr=%q", test.args, test.wantStatus, status, stderr.String())
+		}
+		if !strings.Contains(stdout.String(), test.wantOut) {
+			t.Fatalf("%v: stdout does not contain %q. got=%q", test.args, test.wantOut, stdout.String())
+		}
+		if test.wantErr == "" && stderr.Len() > 0 {
+			t.Fatalf("%v: unexpected stderr. got=%q", test.args, stderr.String())
+		}
+		if !strings.Contains(stderr.String(), test.wantErr) {
+			t.Fatalf("%v: stderr does not contain %q. got=%q", test.args, test.wantErr, stderr.String())
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"glox/ast"
+	"glox/capability"
+	"glox/exception"
+	"glox/format"
+	"glox/lexer"
+	"glox/lox"
+	"glox/parser"
+	"glox/resolver"
+	"glox/source"
+	"glox/token"
+	"os"
+)
+
+// Runs a script, the code given with `-e` or the program read from stdin with `-`. The remaining arguments
+// are passed to the script.
+func (c *CLI) run(args []string) int {
+	flags := c.flags("run")
+	optimize := flags.Bool("O", false, "fold constant expressions and remove dead code before running")
+	code := flags.String("e", "", "run the given code instead of a script")
+	caps := capabilities(flags)
+	if status, ok := c.parse(flags, args); !ok {
+		return status
+	}
+
+	isInline := false
+	flags.Visit(func(f *flag.Flag) { isInline = isInline || f.Name == "e" })
+	if !isInline && flags.NArg() == 0 {
+		return c.usageError(flags, "no script to run")
+	}
+
+	scriptArgs := flags.Args()
+	if !isInline {
+		scriptArgs = scriptArgs[1:]
+	}
+	runner := lox.NewRunner(c.stderr, c.stdout, lox.Options{Optimize: *optimize, Args: scriptArgs, Capabilities: *caps})
+
+	if isInline {
+		return c.status(runner.Run(source.New(source.EVAL, *code)))
+	}
+	src, err := c.load(flags.Arg(0))
+	if err != nil {
+		return c.status(err)
+	}
+	return c.status(runner.Run(src))
+}
+
+func (c *CLI) repl(args []string) int {
+	flags := c.flags("repl")
+	optimize := flags.Bool("O", false, "fold constant expressions and remove dead code before running")
+	prompt := flags.String("prompt", lox.PROMPT, "text shown before each statement")
+	caps := capabilities(flags)
+	if status, ok := c.parse(flags, args); !ok {
+		return status
+	}
+	if flags.NArg() > 0 {
+		return c.usageError(flags, "repl does not take arguments")
+	}
+
+	lox.NewRunner(c.stderr, c.stdout, lox.Options{Optimize: *optimize, Prompt: *prompt, Capabilities: *caps}).StartREPL(c.stdin)
+	return 0
+}
+
+// Defines the `--allow-*` flags granting capabilities to the scripts, they are all denied by default.
+func capabilities(flags *flag.FlagSet) *capability.Set {
+	caps := &capability.Set{}
+	flags.Var(&caps.Read, "allow-read", "allow reading files and importing modules, or only the `paths` given as a comma separated list")
+	flags.Var(&caps.Write, "allow-write", "allow writing files, or only the `paths` given as a comma separated list")
+	flags.Var(&caps.Env, "allow-env", "allow reading environment variables, or only the `names` given")
+	flags.Var(&caps.Run, "allow-run", "allow running programs, or only the `programs` given")
+	return caps
+}
+
+// Lexes, parses and resolves scripts without running them.
+func (c *CLI) check(args []string) int {
+	flags := c.flags("check")
+	if status, ok := c.parse(flags, args); !ok {
+		return status
+	}
+	if flags.NArg() == 0 {
+		return c.usageError(flags, "no script to check")
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		src, err := c.load(path)
+		if err != nil {
+			fmt.Fprintf(c.stderr, "%v\n", err)
+			status = EX_IOERR
+			continue
+		}
+		stmts, err := parse(src)
+		if err != nil {
+			fmt.Fprintf(c.stderr, "%v\n", err)
+			status = EX_DATAERR
+			continue
+		}
+		resolver.New().Resolve(stmts)
+	}
+	return status
+}
+
+// Formats the given scripts, or stdin when there are none. By default the formatted code is printed,
+// `--check` lists the scripts that are not formatted and `--write` rewrites them in place.
+func (c *CLI) fmt(args []string) int {
+	flags := c.flags("fmt")
+	check := flags.Bool("check", false, "list scripts whose formatting differs and exit with 1 if there are any")
+	write := flags.Bool("write", false, "write the formatted code back to the scripts")
+	if status, ok := c.parse(flags, args); !ok {
+		return status
+	}
+	if *check && *write {
+		return c.usageError(flags, "--check and --write cannot be used together")
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+
+	status := 0
+	for _, path := range paths {
+		src, name, err := c.read(path)
+		if err != nil {
+			fmt.Fprintf(c.stderr, "%v\n", err)
+			status = EX_IOERR
+			continue
+		}
+		formatted, err := format.Source(src)
+		if err != nil {
+			fmt.Fprintf(c.stderr, "%v\n", exception.Locate(err, name))
+			status = EX_DATAERR
+			continue
+		}
+
+		switch {
+		case *check:
+			if formatted != src {
+				fmt.Fprintln(c.stdout, name)
+				status = 1
+			}
+		case *write && path != "-":
+			if formatted != src {
+				if err := os.WriteFile(path, []byte(formatted), 0644); err != nil {
+					fmt.Fprintf(c.stderr, "%v\n", err)
+					status = EX_IOERR
+				}
+			}
+		default:
+			fmt.Fprint(c.stdout, formatted)
+		}
+	}
+	return status
+}
+
+// Prints every token of a script, including illegal ones and comments. Lexing errors are reported after
+// the tokens that could be produced.
+func (c *CLI) tokens(args []string) int {
+	flags := c.flags("tokens")
+	asJSON := flags.Bool("json", false, "print the tokens as JSON")
+	if status, ok := c.parse(flags, args); !ok {
+		return status
+	}
+	if flags.NArg() != 1 {
+		return c.usageError(flags, "expected exactly one script")
+	}
+
+	src, err := c.load(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(c.stderr, "%v\n", err)
+		return EX_IOERR
+	}
+	tokens, lexErr := lexer.New(src.Code).Tokenize()
+
+	if *asJSON {
+		data, err := json.MarshalIndent(tokens, "", "  ")
+		if err != nil {
+			fmt.Fprintf(c.stderr, "%v\n", err)
+			return 1
+		}
+		fmt.Fprintln(c.stdout, string(data))
+	} else {
+		token.Fprint(c.stdout, tokens)
+	}
+
+	if lexErr != nil {
+		fmt.Fprintf(c.stderr, "%v\n", exception.Locate(lexErr, src.Name))
+		return EX_DATAERR
+	}
+	return 0
+}
+
+// Prints the syntax tree of a script as JSON.
+func (c *CLI) ast(args []string) int {
+	flags := c.flags("ast")
+	asJSON := flags.Bool("json", false, "print the syntax tree as JSON")
+	if status, ok := c.parse(flags, args); !ok {
+		return status
+	}
+	if !*asJSON || flags.NArg() != 1 {
+		return c.usageError(flags, "expected --json and exactly one script")
+	}
+
+	src, err := c.load(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(c.stderr, "%v\n", err)
+		return EX_IOERR
+	}
+	stmts, err := parse(src)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "%v\n", err)
+		return EX_DATAERR
+	}
+
+	data, err := ast.MarshalProgramIndent(stmts, "  ")
+	if err != nil {
+		fmt.Fprintf(c.stderr, "%v\n", err)
+		return 1
+	}
+	fmt.Fprintln(c.stdout, string(data))
+	return 0
+}
+
+func (c *CLI) version(args []string) int {
+	flags := c.flags("version")
+	if status, ok := c.parse(flags, args); !ok {
+		return status
+	}
+	fmt.Fprintf(c.stdout, "glox %s\n", Version)
+	return 0
+}
+
+func (c *CLI) help(args []string) int {
+	flags := c.flags("help")
+	if status, ok := c.parse(flags, args); !ok {
+		return status
+	}
+	if flags.NArg() == 0 {
+		c.usage(c.stdout)
+		return 0
+	}
+
+	cmd, found := lookup(flags.Arg(0))
+	if !found || flags.NArg() > 1 {
+		fmt.Fprintf(c.stderr, "unknown command %q\n\n", flags.Arg(0))
+		c.usage(c.stderr)
+		return EX_USAGE
+	}
+	return cmd.run(c, []string{"-help"})
+}
+
+// Errors are located in the source.
+func parse(src *source.Source) ([]ast.Statement, error) {
+	tokens, err := lexer.New(src.Code).Tokenize()
+	if err != nil {
+		return nil, exception.Locate(err, src.Name)
+	}
+	stmts, err := parser.New(tokens).Parse()
+	return stmts, exception.Locate(err, src.Name)
+}
@@ -3,42 +3,97 @@ package env
 import (
 	"glox/exception"
 	"glox/token"
+	"glox/value"
 )
 
+// Environment stores the values of a scope in a slice, variables are read and written through the
+// (depth, slot) pair computed by the resolver. Only the global environment keeps a name table because
+// globals can be declared after the code referencing them has been resolved (e.g. in the REPL).
 type Environment struct {
-	values    map[string]any
+	values    []value.Value
+	names     map[string]int
 	enclosing *Environment
 }
 
 func Global() *Environment {
-	return New(nil)
+	return &Environment{values: make([]value.Value, 0), names: make(map[string]int)}
 }
 
-func New(enclosing *Environment) *Environment {
-	return &Environment{values: make(map[string]any), enclosing: enclosing}
+// Creates a local environment with `size` slots.
+func New(enclosing *Environment, size int) *Environment {
+	return &Environment{values: make([]value.Value, size), enclosing: enclosing}
 }
 
-func (env *Environment) Define(name string, value any) {
-	env.values[name] = value
+// Defines a variable by name in the nearest environment that has a name table.
+func (env *Environment) Define(name string, val value.Value) {
+	table := env.table()
+	if slot, isOk := table.names[name]; isOk {
+		table.values[slot] = val
+		return
+	}
+	table.names[name] = len(table.values)
+	table.values = append(table.values, val)
 }
 
-func (env *Environment) Get(name token.Token) any {
-	if val, isOk := env.values[name.Lexeme]; isOk {
-		return val
+// Names returns the variables defined in the nearest environment that has a name table, in the order they
+// were first defined.
+func (env *Environment) Names() []string {
+	table := env.table()
+	if table == nil {
+		return nil
+	}
+	names := make([]string, len(table.names))
+	for name, slot := range table.names {
+		names[slot] = name
 	}
-	if env.enclosing != nil {
-		return env.enclosing.Get(name)
+	return names
+}
+
+func (env *Environment) Get(name token.Token) (value.Value, error) {
+	if table := env.table(); table != nil {
+		if slot, isOk := table.names[name.Lexeme]; isOk {
+			return table.values[slot], nil
+		}
+	}
+	return value.Nil, exception.Runtime(name, "undefined variable '"+name.Lexeme+"'.")
+}
+
+func (env *Environment) Assign(name token.Token, val value.Value) error {
+	if table := env.table(); table != nil {
+		if slot, isOk := table.names[name.Lexeme]; isOk {
+			table.values[slot] = val
+			return nil
+		}
 	}
 	return exception.Runtime(name, "undefined variable '"+name.Lexeme+"'.")
 }
 
-func (env *Environment) Assign(name token.Token, value any) error {
-	if _, isOk := env.values[name.Lexeme]; !isOk {
-		if env.enclosing != nil {
-			return env.enclosing.Assign(name, value)
+// Sets the value of a slot in this environment.
+func (env *Environment) Set(slot int, val value.Value) {
+	env.values[slot] = val
+}
+
+func (env *Environment) GetAt(depth int, slot int) value.Value {
+	return env.ancestor(depth).values[slot]
+}
+
+func (env *Environment) AssignAt(depth int, slot int, val value.Value) {
+	env.ancestor(depth).values[slot] = val
+}
+
+func (env *Environment) ancestor(depth int) *Environment {
+	current := env
+	for i := 0; i < depth; i++ {
+		current = current.enclosing
+	}
+	return current
+}
+
+func (env *Environment) table() *Environment {
+	for current := env; current != nil; current = current.enclosing {
+		if current.names != nil {
+			return current
 		}
-		return exception.Runtime(name, "undefined variable '"+name.Lexeme+"'.")
 	}
-	env.values[name.Lexeme] = value
 	return nil
 }
@@ -2,6 +2,7 @@ package env
 
 import (
 	"glox/token"
+	"glox/value"
 	"math/rand"
 	"strings"
 	"testing"
@@ -9,27 +10,35 @@ import (
 
 func TestDefine(t *testing.T) {
 	global := Global()
-	vars := map[string]any{
-		"age":     25,
-		"name":    `"boris"`,
-		"isHuman": true,
+	vars := map[string]value.Value{
+		"age":     value.Number(25),
+		"name":    value.String(`"boris"`),
+		"isHuman": value.Bool(true),
 	}
 
-	for name, value := range vars {
-		global.Define(name, value)
-		if global.values[name] != value {
-			t.Fatalf("failed to define a variable %q with a value. expected=%v got=%v", name, value, global.values[name])
+	for name, val := range vars {
+		global.Define(name, val)
+		if global.values[global.names[name]] != val {
+			t.Fatalf("failed to define a variable %q with a value. expected=%v got=%v", name, val, global.values[global.names[name]])
 		}
 	}
 
-	local := New(global)
+	global.Define("age", value.Number(26))
+	if len(global.values) != len(vars) {
+		t.Fatalf("redefining a variable must reuse its slot. expected=%d slots got=%d", len(vars), len(global.values))
+	}
+
+	local := New(global, 1)
 	if local.enclosing != global {
 		t.Fatalf("local env must have a reference to its enclosing env. expected=%v got=%v", global, local.enclosing)
 	}
+	if local.names != nil {
+		t.Fatalf("local env must not have a name table. got=%v", local.names)
+	}
 
-	expected := "bob"
-	local.Define("name", expected)
-	got := local.values["name"]
+	expected := value.String("bob")
+	local.Set(0, expected)
+	got := local.values[0]
 	if got != expected {
 		t.Fatalf("failed to shadow variable in local scope. got='%v'. expected='%s'", got, expected)
 	}
@@ -37,24 +46,24 @@ func TestDefine(t *testing.T) {
 
 func TestGet(t *testing.T) {
 	global := Global()
-	vars := map[string]any{
-		"age":     25,
-		"name":    `"boris"`,
-		"isHuman": true,
+	vars := map[string]value.Value{
+		"age":     value.Number(25),
+		"name":    value.String(`"boris"`),
+		"isHuman": value.Bool(true),
 	}
-	for name, value := range vars {
-		global.Define(name, value)
+	for name, val := range vars {
+		global.Define(name, val)
 		tok := token.Token{Type: token.IDENTIFIER, Lexeme: name, Literal: nil, Line: 1}
-		got := global.Get(tok)
+		got, err := global.Get(tok)
 
-		if got != value {
-			t.Fatalf("failed to get variable %q. expected=%v got=%v", name, value, got)
+		if err != nil || got != val {
+			t.Fatalf("failed to get variable %q. expected=%v got=%v", name, val, got)
 		}
 	}
 	undefined := token.Token{Type: token.IDENTIFIER, Lexeme: "undefined", Literal: nil, Line: 1}
-	got := global.Get(undefined)
+	got, err := global.Get(undefined)
 
-	if err, isErr := got.(error); isErr {
+	if err != nil {
 		if !(strings.Contains(err.Error(), "undefined variable") && strings.Contains(err.Error(), "RuntimeException")) {
 			t.Fatalf("wrong error message. expected a RuntimeException with message 'undefined variable'. got=%q", err.Error())
 		}
@@ -62,7 +71,7 @@ func TestGet(t *testing.T) {
 		t.Fatalf("failed to capture 'undefined variable'exception. got=%v", got)
 	}
 
-	local := New(global)
+	local := New(global, 1)
 
 	if local.enclosing != global {
 		t.Fatalf("non-global scope must have a reference to their enclosing scope. got='%v' expected='%v'", local.enclosing, global)
@@ -70,46 +79,54 @@ func TestGet(t *testing.T) {
 
 	for name := range vars {
 		tok := token.Token{Type: token.IDENTIFIER, Lexeme: name, Literal: nil, Line: 1}
-		got = local.Get(tok)
-		expected := global.Get(tok)
+		got, _ = local.Get(tok)
+		expected, _ := global.Get(tok)
 
 		if expected != got {
 			t.Fatalf("failed to get variable from enclosing environment in local scope. expected='%v'. got='%v'", expected, got)
 		}
 	}
 
-	expected := rand.Int()
-	local.Define(undefined.Lexeme, expected)
-	got = local.Get(undefined)
+	expected := value.Number(float64(rand.Int()))
+	local.Set(0, expected)
+	got = local.GetAt(0, 0)
 
 	if expected != got {
 		t.Fatalf("failed to get variable defined in local environment. got='%v' expected='%v'", got, expected)
 	}
 
-	gotGlobal := global.Get(undefined)
-	if _, isErr := gotGlobal.(error); !isErr {
+	gotGlobal, err := global.Get(undefined)
+	if err == nil {
 		t.Fatalf("variable defined in local scope should not be accessible in enclosing scope. got='%v'. expected='RuntimeException'", gotGlobal)
 	}
 
+	inner := New(local, 2)
+	inner.Set(1, value.String("inner"))
+	if got := inner.GetAt(1, 0); got != expected {
+		t.Fatalf("failed to get variable from enclosing local environment. got='%v' expected='%v'", got, expected)
+	}
+	if got := inner.GetAt(0, 1); got != value.String("inner") {
+		t.Fatalf("failed to get variable by slot. got='%v' expected='%v'", got, "inner")
+	}
 }
 
 func TestAssign(t *testing.T) {
 	global := Global()
-	global.Define("name", `"boris"`)
+	global.Define("name", value.String(`"boris"`))
 	tok := token.Token{Type: token.IDENTIFIER, Lexeme: "name", Literal: nil, Line: 1}
 
-	err := global.Assign(tok, "anya")
+	err := global.Assign(tok, value.String("anya"))
 
 	if err != nil {
 		t.Fatalf("failed to assign new value to variable=%v. got=%s", "name", err.Error())
 	}
 
-	if global.values["name"] != "anya" {
-		t.Fatalf("failed to assign new value to variable. expected=%v got=%v", "anya", global.values["name"])
+	if global.values[global.names["name"]] != value.String("anya") {
+		t.Fatalf("failed to assign new value to variable. expected=%v got=%v", "anya", global.values[global.names["name"]])
 	}
 	undefined := token.Token{Type: token.IDENTIFIER, Lexeme: "nothing", Literal: nil, Line: 1}
 
-	err = global.Assign(undefined, 12)
+	err = global.Assign(undefined, value.Number(12))
 	if err == nil {
 		t.Fatal("assigning an undefined variable should results on an error.")
 	}
@@ -119,17 +136,36 @@ func TestAssign(t *testing.T) {
 		t.Fatalf("wrong error message for undefined variable. got='%s' expected contains=['%s', '%s']", msg, "undefined variable", undefined.Lexeme)
 	}
 
-	local := New(global)
+	local := New(global, 1)
 
 	if local.enclosing != global {
 		t.Fatalf("non-global scope must have a reference to their enclosing scope. got='%v' expected='%v'", local.enclosing, global)
 	}
 
-	expected := "bob"
+	expected := value.String("bob")
 	local.Assign(tok, expected)
-	got := local.Get(tok)
+	got, _ := local.Get(tok)
 
 	if expected != got {
 		t.Fatalf("failed to assign value to global variable inside local scope. got='%v' expected='%v'", got, expected)
 	}
+
+	inner := New(local, 0)
+	inner.AssignAt(1, 0, value.Number(42))
+	if got := local.GetAt(0, 0); got != value.Number(42) {
+		t.Fatalf("failed to assign value to enclosing local variable. got='%v' expected='%v'", got, 42)
+	}
+}
+
+func TestNames(t *testing.T) {
+	global := Global()
+	for _, name := range []string{"clock", "age", "name"} {
+		global.Define(name, value.Nil)
+	}
+	global.Define("clock", value.Number(1))
+
+	local := New(New(global, 2), 1)
+	if got := strings.Join(local.Names(), ","); got != "clock,age,name" {
+		t.Fatalf("wrong names. want='clock,age,name' got=%q", got)
+	}
 }
@@ -1,30 +1,145 @@
 package exception
 
 import (
+	"errors"
 	"fmt"
 	"glox/token"
+	"strings"
 )
 
 const (
 	PARSE_EXCEPTION   = "ParseException"
 	RUNTIME_EXCEPTION = "RuntimeException"
 	GENERIC_EXCEPTION = "GenericException"
+	LIMIT_EXCEPTION   = "LimitException"
+	THROWN_EXCEPTION  = "Exception" // Raised by a `throw` statement.
 )
 
-func Generic(line int, where string, msg string) error {
-	out := fmt.Sprintf("%s(%s at %s)", GENERIC_EXCEPTION, msg, where)
-	return fmt.Errorf("unhandled exception: %s\n[line %d]", out, line)
+// Causes of the LimitException raised when a program goes over the limits set by the host. When the context
+// of the program is done, its error is the cause instead, e.g. context.DeadlineExceeded.
+var (
+	ErrStatementLimit = errors.New("statement limit exceeded")
+	ErrCallDepthLimit = errors.New("call depth limit exceeded")
+)
+
+// Error is an exception raised while lexing, parsing or running a program. Once the runner knows which
+// source the program comes from it is reported as `script.lox:12:5: ...`, otherwise the line follows it.
+type Error struct {
+	Kind    string // One of the *_EXCEPTION constants.
+	Line    int
+	Column  int    // 0 when unknown.
+	Source  string // Name of the source the program comes from, empty when unknown.
+	Message string
+	Reason  string // The message without the kind and the token, e.g. "undefined variable 'x'.".
+	AtEnd   bool   // Raised because the program ended too early (e.g. a block is not closed), more code might fix it.
+	Cause   error
+}
+
+func (e *Error) Error() string {
+	if e.Line == 0 {
+		// Not raised by a particular token, e.g. a program stopped by the host.
+		return strings.TrimPrefix(e.Source+": unhandled exception: "+e.Message, ": ")
+	}
+	if e.Source == "" {
+		return fmt.Sprintf("unhandled exception: %s\n[line: %d]", e.Message, e.Line)
+	}
+	return fmt.Sprintf("%s:%d:%d: unhandled exception: %s", e.Source, e.Line, e.Column, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+func Generic(line int, column int, where string, msg string) error {
+	return &Error{Kind: GENERIC_EXCEPTION, Line: line, Column: column, Message: fmt.Sprintf("%s(%s at %s)", GENERIC_EXCEPTION, msg, where), Reason: msg}
 }
 
 // Calls `Generic` with an empty string for the `where` argument.
-func Short(line int, msg string) error {
-	return Generic(line, "", msg)
+func Short(line int, column int, msg string) error {
+	return Generic(line, column, "", msg)
+}
+
+func Runtime(tok token.Token, message string) error {
+	return &Error{
+		Kind:    RUNTIME_EXCEPTION,
+		Line:    tok.Line,
+		Column:  tok.Column,
+		Message: fmt.Sprintf("%s(%q, %s)", RUNTIME_EXCEPTION, tok.Lexeme, message),
+		Reason:  message,
+		AtEnd:   tok.Type == token.EOF,
+	}
+}
+
+// Wrap raises a runtime exception at tok for an error returned by Go code, e.g. a native function. The error
+// stays available to `errors.Is` and `errors.As`.
+func Wrap(tok token.Token, err error) error {
+	e := Runtime(tok, err.Error()).(*Error)
+	e.Cause = err
+	return e
+}
+
+// Limit stops a program, the cause is either one of the Err*Limit errors or the error of the program's
+// context. The token is where the program stopped when it is known, it may be zero.
+func Limit(tok token.Token, cause error) error {
+	msg := fmt.Sprintf("%s(%s)", LIMIT_EXCEPTION, cause)
+	if tok.Lexeme != "" {
+		msg = fmt.Sprintf("%s(%q, %s)", LIMIT_EXCEPTION, tok.Lexeme, cause)
+	}
+	return &Error{Kind: LIMIT_EXCEPTION, Line: tok.Line, Column: tok.Column, Message: msg, Reason: cause.Error(), Cause: cause}
 }
 
-func Runtime(token token.Token, message string) error {
-	return fmt.Errorf("unhandled exception: %s(%q, %s)\n[line: %d]", RUNTIME_EXCEPTION, token.Lexeme, message, token.Line)
+// IsLimit tells whether err stopped a program that went over its limits or whose context is done.
+func IsLimit(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Kind == LIMIT_EXCEPTION
+}
+
+// Throw raises the exception of a `throw` statement at tok, the cause holds the thrown value.
+func Throw(tok token.Token, message string, cause error) error {
+	return &Error{
+		Kind:    THROWN_EXCEPTION,
+		Line:    tok.Line,
+		Column:  tok.Column,
+		Message: fmt.Sprintf("%s(%s)", THROWN_EXCEPTION, message),
+		Reason:  message,
+		Cause:   cause,
+	}
 }
 
 func Parse(tok token.Token) error {
-	return fmt.Errorf("unhandled exception: %s(%q, illegal token)\n[line: %d]", PARSE_EXCEPTION, tok.Lexeme, tok.Line)
+	return &Error{
+		Kind:    PARSE_EXCEPTION,
+		Line:    tok.Line,
+		Column:  tok.Column,
+		Message: fmt.Sprintf("%s(%q, illegal token)", PARSE_EXCEPTION, tok.Lexeme),
+		Reason:  "illegal token",
+		AtEnd:   tok.Type == token.EOF,
+	}
+}
+
+// AtEnd marks an exception as raised because the program ended too early.
+func AtEnd(err error) error {
+	var e *Error
+	if errors.As(err, &e) {
+		e.AtEnd = true
+	}
+	return err
+}
+
+// IsAtEnd tells whether err was raised because the program ended too early.
+func IsAtEnd(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.AtEnd
+}
+
+// Locate names the source an exception was raised in. Exceptions that already know their source and other
+// errors are returned as they are.
+func Locate(err error, source string) error {
+	var e *Error
+	if !errors.As(err, &e) || e.Source != "" || source == "" {
+		return err
+	}
+	located := *e
+	located.Source = source
+	return &located
 }
@@ -0,0 +1,177 @@
+package format
+
+import (
+	"bytes"
+	"glox/lexer"
+	"glox/parser"
+	"glox/source"
+	"glox/token"
+	"strings"
+)
+
+const INDENT = "    "
+
+// Source returns the canonical formatting of a Lox program. The program is parsed first so that invalid
+// code is reported instead of being formatted.
+//
+// Formatting works on the token stream rather than on the AST: the parser desugars some statements (e.g.
+// `for` loops) and the AST has no comments, both of which must be kept as written. A `#!` line is kept as
+// it is, the lines of errors are counted from the top of the file, including it.
+func Source(src string) (string, error) {
+	// The byte order mark is dropped, the `#!` line is put back as it is after the code is formatted.
+	code := source.New("", src).Code
+	shebang := ""
+	if src = strings.TrimPrefix(src, source.BOM); strings.HasPrefix(src, "#!") {
+		line, _, _ := strings.Cut(src, "\n")
+		shebang = strings.TrimSuffix(line, "\r") + "\n"
+	}
+
+	tokens, err := lexer.New(code).Tokenize()
+	if err != nil {
+		return "", err
+	}
+	if _, err := parser.New(tokens).Parse(); err != nil {
+		return "", err
+	}
+
+	f := &formatter{tokens: tokens}
+	return shebang + f.format(), nil
+}
+
+type formatter struct {
+	tokens   []token.Token
+	out      bytes.Buffer
+	indent   int
+	parens   int          // Open parentheses, a `;` inside them (e.g. in a `for` clause) does not end the line.
+	newlines int          // Line breaks to write before the next token, 2 leaves a blank line.
+	unary    bool         // The last token written was a prefix operator.
+	operand  bool         // The last code token written ends an operand, so a following `-` is a binary operator.
+	prev     *token.Token // Last token or comment written, comments are turned into tokens of their trivia type.
+}
+
+func (f *formatter) format() string {
+	for i, tok := range f.tokens {
+		for _, trivia := range tok.Leading {
+			f.comment(trivia, false)
+		}
+		if tok.Type == token.EOF {
+			break
+		}
+
+		switch {
+		case tok.Type == token.L_BRACE:
+			f.write(tok)
+			f.indent++
+			f.newlines = 1
+		case tok.Type == token.R_BRACE:
+			f.indent--
+			if f.prev != nil && f.prev.Type == token.L_BRACE {
+				f.newlines = 0
+			} else {
+				f.newlines = 1
+			}
+			f.write(tok)
+			if next := f.tokens[i+1]; next.Type != token.ELSE && next.Type != token.CATCH && next.Type != token.FINALLY {
+				f.newlines = 1
+			}
+		case tok.Type == token.SEMICOLON:
+			f.write(tok)
+			if f.parens == 0 {
+				f.newlines = 1
+			}
+		case tok.Type == token.L_PAREN:
+			f.write(tok)
+			f.parens++
+		case tok.Type == token.R_PAREN:
+			f.parens--
+			f.write(tok)
+		default:
+			isUnary := tok.Type == token.BANG || (tok.Type == token.MINUS && !f.operand)
+			f.write(tok)
+			f.unary = isUnary
+		}
+
+		for _, trivia := range tok.Trailing {
+			f.comment(trivia, true)
+		}
+	}
+
+	f.out.WriteString("\n")
+	return strings.TrimLeft(f.out.String(), "\n")
+}
+
+func (f *formatter) comment(trivia token.Trivia, trailing bool) {
+	text := strings.TrimRight(trivia.Lexeme, " \t\r")
+	tok := token.Token{Type: trivia.Type, Lexeme: text, Line: trivia.Line + strings.Count(text, "\n")}
+
+	if trailing {
+		f.out.WriteString(" " + text)
+	} else {
+		if f.newlines == 0 && f.prev != nil {
+			f.newlines = 1
+		}
+		f.writeText(tok, text)
+	}
+	f.prev = &tok
+	f.unary = false
+
+	// A line comment always ends the line, so does a block comment standing on its own line.
+	if tok.Type == token.SLASH_SLASH || !trailing {
+		f.newlines = 1
+	}
+}
+
+func (f *formatter) write(tok token.Token) {
+	f.writeText(tok, tok.Lexeme)
+	f.prev = &tok
+	f.unary = false
+	f.operand = isOperand(tok)
+}
+
+func (f *formatter) writeText(tok token.Token, text string) {
+	if f.newlines > 0 {
+		// Keeps a single blank line between statements when the source had at least one.
+		if f.prev != nil && startLine(tok)-f.prev.Line > 1 && f.prev.Type != token.L_BRACE && tok.Type != token.R_BRACE {
+			f.newlines = 2
+		}
+		f.out.WriteString(strings.Repeat("\n", f.newlines))
+		f.out.WriteString(strings.Repeat(INDENT, f.indent))
+		f.newlines = 0
+	} else if f.prev != nil && f.needsSpace(tok) {
+		f.out.WriteString(" ")
+	}
+	f.out.WriteString(text)
+}
+
+func (f *formatter) needsSpace(tok token.Token) bool {
+	if f.unary {
+		return false
+	}
+	switch f.prev.Type {
+	case token.L_PAREN, token.DOT:
+		return false
+	case token.L_BRACE:
+		return tok.Type != token.R_BRACE
+	}
+	switch tok.Type {
+	case token.R_PAREN, token.COMMA, token.SEMICOLON, token.DOT:
+		return false
+	case token.L_PAREN:
+		// Calls are written without space, e.g. `greet("anya")`, keywords are followed by one.
+		return !f.operand || f.prev.Type == token.NUMBER || f.prev.Type == token.STRING
+	}
+	return true
+}
+
+func isOperand(tok token.Token) bool {
+	switch tok.Type {
+	case token.IDENTIFIER, token.NUMBER, token.STRING, token.TRUE, token.FALSE, token.NIL, token.THIS, token.SUPER, token.R_PAREN:
+		return true
+	}
+	return false
+}
+
+// Tokens record the line they end on, multi-line strings and comments start earlier.
+func startLine(tok token.Token) int {
+	return tok.Line - strings.Count(tok.Lexeme, "\n")
+}
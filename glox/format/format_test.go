@@ -0,0 +1,67 @@
+package format
+
+import (
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{code: `let   age=15 ;`, want: "let age = 15;\n"},
+		{code: `print -age+-(2*3);`, want: "print -age + -(2 * 3);\n"},
+		{code: `print !(1==2) or clock()>0;`, want: "print !(1 == 2) or clock() > 0;\n"},
+		{code: `var x=a?b:c;x=x-1;`, want: "var x = a ? b : c;\nx = x - 1;\n"},
+		{code: `fun greet(name,greeting){print greeting+name;}greet("anya" , "hi");`, want: "fun greet(name, greeting) {\n    print greeting + name;\n}\ngreet(\"anya\", \"hi\");\n"},
+		{code: "if(a){print 1;}\n  else{ print 2;}", want: "if (a) {\n    print 1;\n} else {\n    print 2;\n}\n"},
+		{code: `if (a) print 1; else print 2;`, want: "if (a) print 1;\nelse print 2;\n"},
+		{code: "try{throw \"x\";}\ncatch(e){print e.message;}finally{print 1;}", want: "try {\n    throw \"x\";\n} catch (e) {\n    print e.message;\n} finally {\n    print 1;\n}\n"},
+		{code: `for(let i=0;i<3;i=i+1){while(true){break;}}`, want: "for (let i = 0; i < 3; i = i + 1) {\n    while (true) {\n        break;\n    }\n}\n"},
+		{code: `for(;;){}`, want: "for (;;) {}\n"},
+		{code: "let a = 1;\n\n\n\nlet b = 2;", want: "let a = 1;\n\nlet b = 2;\n"},
+		{code: "{\n\n  let a = 1;\n\n}", want: "{\n    let a = 1;\n}\n"},
+		{code: "let a = \"multi\nline\";\nprint a;", want: "let a = \"multi\nline\";\nprint a;\n"},
+		{code: "// header\nlet a = 1;   // trailing  \nprint a;", want: "// header\nlet a = 1; // trailing\nprint a;\n"},
+		{code: "{ // opening\n  print 1;\n    // last\n}", want: "{ // opening\n    print 1;\n    // last\n}\n"},
+		{code: "/* block\n   comment */\nprint 1;\n", want: "/* block\n   comment */\nprint 1;\n"},
+		{code: "print 1 /* inline */ + 2;", want: "print 1 /* inline */ + 2;\n"},
+		{code: "#!/usr/bin/env glox\nprint   1;", want: "#!/usr/bin/env glox\nprint 1;\n"},
+		{code: "\uFEFFprint   1;", want: "print 1;\n"},
+		{code: "\uFEFF#!/usr/bin/env glox\r\nprint   1;", want: "#!/usr/bin/env glox\nprint 1;\n"},
+		{code: "#!/usr/bin/env glox", want: "#!/usr/bin/env glox\n"},
+		{code: "/* a /* nested */ * comment */\nprint 1; // end\n// last words", want: "/* a /* nested */ * comment */\nprint 1; // end\n// last words\n"},
+	}
+
+	for _, test := range tests {
+		got, err := Source(test.code)
+		if err != nil {
+			t.Fatalf("`%s` -> failed to format. got error='%s'", test.code, err.Error())
+		}
+		if got != test.want {
+			t.Fatalf("`%s` -> wrong formatting.\nwant=%q\ngot=%q", test.code, test.want, got)
+		}
+
+		again, err := Source(got)
+		if err != nil {
+			t.Fatalf("`%s` -> failed to format formatted code. got error='%s'", got, err.Error())
+		}
+		if again != got {
+			t.Fatalf("`%s` -> formatting is not idempotent.\nfirst=%q\nsecond=%q", test.code, got, again)
+		}
+	}
+}
+
+func TestSourceInvalid(t *testing.T) {
+	tests := []string{
+		`let = 12;`,
+		`print (1 + 2;`,
+		`{ print 1;`,
+	}
+
+	for _, code := range tests {
+		if got, err := Source(code); err == nil {
+			t.Fatalf("`%s` -> invalid code must not be formatted. got=%q", code, got)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package host
+
+import (
+	"fmt"
+	"glox/interpreter"
+	"glox/native"
+	"glox/value"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type Options struct {
+	ReadOnly bool     // Properties cannot be assigned from Lox.
+	Allow    []string // When not empty, only these properties can be used, other ones are undefined.
+}
+
+// Object exposes a Go struct or map with string keys to Lox. The exported fields and methods of a struct
+// are properties, `order.total` reads the field `Total` and `order.addItem(x)` calls the method `AddItem`.
+// Names that differ in more than their first letter are matched ignoring case, e.g. `order.id` reads `ID`.
+// The keys of a map are its properties. Values are converted when they cross the boundary, nested structs
+// and maps are exposed with the same options.
+type Object struct {
+	val  reflect.Value
+	opts Options
+}
+
+// New wraps a struct, a pointer to a struct or a map with string keys. Pass a pointer for the assignments
+// made by scripts to be seen by Go, a struct is copied.
+func New(v any, opts Options) (*Object, error) {
+	val := reflect.ValueOf(v)
+	if !isObject(val) {
+		return nil, fmt.Errorf("%T cannot be exposed as a Lox object", v)
+	}
+	if val.Kind() == reflect.Struct {
+		copied := reflect.New(val.Type())
+		copied.Elem().Set(val)
+		val = copied
+	}
+	return &Object{val: val, opts: opts}, nil
+}
+
+func (o *Object) Get(name string) (value.Value, error) {
+	if !o.allowed(name) {
+		return value.Nil, undefined(name)
+	}
+
+	if o.val.Kind() == reflect.Map {
+		elem := o.val.MapIndex(reflect.ValueOf(name).Convert(o.val.Type().Key()))
+		if !elem.IsValid() {
+			return value.Nil, undefined(name)
+		}
+		return o.value(elem)
+	}
+
+	if method := o.method(name); method.IsValid() {
+		fn, err := native.Func[*interpreter.Interpreter](name, method.Interface())
+		if err != nil {
+			return value.Nil, err
+		}
+		return value.Object(fn), nil
+	}
+	if field, isOk := o.field(name); isOk {
+		if !field.IsValid() {
+			return value.Nil, nil
+		}
+		return o.value(field)
+	}
+	return value.Nil, undefined(name)
+}
+
+func (o *Object) Set(name string, val value.Value) error {
+	if !o.allowed(name) {
+		return undefined(name)
+	}
+	if o.opts.ReadOnly {
+		return fmt.Errorf("property '%s' is read-only.", name)
+	}
+
+	if o.val.Kind() == reflect.Map {
+		elemType := o.val.Type().Elem()
+		converted, err := native.FromValue(val, elemType)
+		if err != nil {
+			return fmt.Errorf("property '%s' expects %s, got %s '%s'.", name, err, val.Kind(), val)
+		}
+		o.val.SetMapIndex(reflect.ValueOf(name).Convert(o.val.Type().Key()), converted)
+		return nil
+	}
+
+	field, isOk := o.field(name)
+	if !isOk {
+		return undefined(name)
+	} else if !field.IsValid() {
+		return fmt.Errorf("property '%s' cannot be assigned, the struct embedding it is nil.", name)
+	} else if !field.CanSet() {
+		return fmt.Errorf("property '%s' is read-only.", name)
+	}
+	converted, err := native.FromValue(val, field.Type())
+	if err != nil {
+		return fmt.Errorf("property '%s' expects %s, got %s '%s'.", name, err, val.Kind(), val)
+	}
+	field.Set(converted)
+	return nil
+}
+
+// Unwrap returns the wrapped value, Go functions receive it when scripts pass them the object.
+func (o *Object) Unwrap() reflect.Value {
+	return o.val
+}
+
+func (o *Object) String() string {
+	return fmt.Sprintf("<host %s>", o.val.Type())
+}
+
+func (o *Object) method(name string) reflect.Value {
+	if method := o.val.MethodByName(exported(name)); method.IsValid() {
+		return method
+	}
+	for n := 0; n < o.val.NumMethod(); n++ {
+		if strings.EqualFold(o.val.Type().Method(n).Name, name) {
+			return o.val.Method(n)
+		}
+	}
+	return reflect.Value{}
+}
+
+// Returns the exported field of the struct, pointers to structs are followed. The field is not valid when it
+// is promoted through a nil embedded pointer, it is nil for Lox.
+func (o *Object) field(name string) (reflect.Value, bool) {
+	val := o.val
+	for val.Kind() == reflect.Pointer {
+		val = val.Elem()
+	}
+	field, isOk := val.Type().FieldByName(exported(name))
+	if !isOk {
+		field, isOk = val.Type().FieldByNameFunc(func(goName string) bool { return strings.EqualFold(goName, name) })
+	}
+	if !isOk || !field.IsExported() {
+		return reflect.Value{}, false
+	}
+	promoted, err := val.FieldByIndexErr(field.Index)
+	if err != nil {
+		return reflect.Value{}, true
+	}
+	return promoted, true
+}
+
+func (o *Object) allowed(name string) bool {
+	if len(o.opts.Allow) == 0 {
+		return true
+	}
+	for _, allowed := range o.opts.Allow {
+		if allowed == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Converts a Go value read from the object, structs and maps become objects themselves.
+func (o *Object) value(val reflect.Value) (value.Value, error) {
+	if val.Kind() == reflect.Interface || val.Kind() == reflect.Pointer {
+		if val.IsNil() {
+			return value.Nil, nil
+		}
+		if val.Kind() == reflect.Interface {
+			val = val.Elem()
+		}
+	}
+	if isObject(val) {
+		if val.Kind() == reflect.Struct && val.CanAddr() {
+			val = val.Addr()
+		}
+		nested, err := New(val.Interface(), o.opts)
+		return value.Object(nested), err
+	}
+	return native.ToValue(val.Interface())
+}
+
+func isObject(val reflect.Value) bool {
+	switch val.Kind() {
+	case reflect.Pointer:
+		return !val.IsNil() && val.Elem().Kind() == reflect.Struct
+	case reflect.Struct:
+		return true
+	case reflect.Map:
+		return !val.IsNil() && val.Type().Key().Kind() == reflect.String
+	}
+	return false
+}
+
+// Lox names start with a lowercase letter, Go exported names with an uppercase one.
+func exported(name string) string {
+	first, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(first)) + name[size:]
+}
+
+func undefined(name string) error {
+	return fmt.Errorf("undefined property '%s'.", name)
+}
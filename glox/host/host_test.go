@@ -0,0 +1,187 @@
+package host
+
+import (
+	"errors"
+	"glox/interpreter"
+	"glox/value"
+	"strings"
+	"testing"
+)
+
+type item struct {
+	Name  string
+	Price float64
+}
+
+type order struct {
+	ID       int
+	Total    float64
+	Customer *item
+	Notes    map[string]any
+	secret   string
+}
+
+func (o *order) AddItem(price float64) (float64, error) {
+	if price < 0 {
+		return 0, errors.New("negative price")
+	}
+	o.Total += price
+	return o.Total, nil
+}
+
+func (o *order) Assign(customer *item) {
+	o.Customer = customer
+}
+
+func (o *order) Bill(customer item) string {
+	return customer.Name
+}
+
+func TestObject(t *testing.T) {
+	o := &order{ID: 7, Customer: &item{Name: "anya"}, Notes: map[string]any{"gift": true}}
+	obj, err := New(o, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error. got=%v", err)
+	}
+
+	if got, err := obj.Get("id"); err != nil || got.AsNumber() != 7 {
+		t.Fatalf("wrong field value. got=%v err=%v", got, err)
+	}
+	if err := obj.Set("total", value.Number(12.5)); err != nil || o.Total != 12.5 {
+		t.Fatalf("the assignment must reach the struct. got=%v err=%v", o.Total, err)
+	}
+
+	method, err := obj.Get("addItem")
+	if err != nil {
+		t.Fatalf("unexpected error. got=%v", err)
+	}
+	addItem := method.AsObject().(interpreter.Callable)
+	if got, err := addItem.Call(nil, []value.Value{value.Number(2.5)}); err != nil || got.AsNumber() != 15 {
+		t.Fatalf("wrong method result. got=%v err=%v", got, err)
+	}
+
+	customer, err := obj.Get("customer")
+	if err != nil {
+		t.Fatalf("unexpected error. got=%v", err)
+	}
+	if err := customer.AsObject().(*Object).Set("name", value.String("bo")); err != nil || o.Customer.Name != "bo" {
+		t.Fatalf("nested structs must be objects too. got=%q err=%v", o.Customer.Name, err)
+	}
+	notes, _ := obj.Get("notes")
+	if gift, err := notes.AsObject().(*Object).Get("gift"); err != nil || !gift.AsBool() {
+		t.Fatalf("maps must be objects. got=%v err=%v", gift, err)
+	}
+	if err := notes.AsObject().(*Object).Set("rush", value.Number(1)); err != nil || o.Notes["rush"] != 1.0 {
+		t.Fatalf("map assignment must reach the map. got=%v err=%v", o.Notes, err)
+	}
+
+	errs := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"unexported field", get(obj, "secret"), "undefined property 'secret'."},
+		{"missing field", get(obj, "nope"), "undefined property 'nope'."},
+		{"wrong type", obj.Set("total", value.String("12")), "property 'total' expects a number, got string '12'."},
+		{"method error", call(addItem, value.Number(-1)), "negative price"},
+	}
+	for _, test := range errs {
+		if test.err == nil || test.err.Error() != test.want {
+			t.Fatalf("%s: wrong error. want=%q got=%v", test.name, test.want, test.err)
+		}
+	}
+
+	if _, err := New(12, Options{}); err == nil {
+		t.Fatalf("numbers cannot be objects")
+	}
+	if got := obj.String(); !strings.Contains(got, "order") {
+		t.Fatalf("wrong string. got=%q", got)
+	}
+}
+
+// Embeds item through a pointer, its fields are promoted.
+type line struct {
+	*item
+	Quantity int
+}
+
+func TestObjectNilEmbedded(t *testing.T) {
+	obj, err := New(&line{Quantity: 2}, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error. got=%v", err)
+	}
+	if got, err := obj.Get("name"); err != nil || !got.IsNil() {
+		t.Fatalf("a field of a nil embedded struct must be nil. got=%v err=%v", got, err)
+	}
+	if err := obj.Set("name", value.String("pen")); err == nil || !strings.Contains(err.Error(), "nil") {
+		t.Fatalf("a field of a nil embedded struct cannot be assigned. got=%v", err)
+	}
+	if got, err := obj.Get("quantity"); err != nil || got.AsNumber() != 2 {
+		t.Fatalf("wrong field value. got=%v err=%v", got, err)
+	}
+
+	l := &line{item: &item{Name: "pen"}}
+	obj, _ = New(l, Options{})
+	if err := obj.Set("price", value.Number(3)); err != nil || l.Price != 3 {
+		t.Fatalf("fields of an embedded struct must be promoted. got=%v err=%v", l.Price, err)
+	}
+}
+
+func TestObjectRoundTrip(t *testing.T) {
+	first := &order{Customer: &item{Name: "anya"}, Notes: map[string]any{}}
+	second := &order{}
+	from, _ := New(first, Options{})
+	to, _ := New(second, Options{})
+
+	customer, _ := from.Get("customer")
+	assign, _ := to.Get("assign")
+	if err := call(assign.AsObject().(interpreter.Callable), customer); err != nil || second.Customer != first.Customer {
+		t.Fatalf("a host object must be passed back to Go as its value. got=%v err=%v", second.Customer, err)
+	}
+	bill, _ := to.Get("bill")
+	if got, err := bill.AsObject().(interpreter.Callable).Call(nil, []value.Value{customer}); err != nil || got.AsString() != "anya" {
+		t.Fatalf("a struct must be passed by value. got=%v err=%v", got, err)
+	}
+
+	notes, _ := from.Get("notes")
+	if err := call(assign.AsObject().(interpreter.Callable), notes); err == nil || !strings.Contains(err.Error(), "expects a *host.item") {
+		t.Fatalf("an object of another type must not be passed. got=%v", err)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	o := &order{ID: 7}
+	readOnly, _ := New(o, Options{ReadOnly: true})
+	if err := readOnly.Set("total", value.Number(1)); err == nil || o.Total != 0 {
+		t.Fatalf("read-only objects cannot be assigned. got=%v", err)
+	}
+	if got, err := readOnly.Get("id"); err != nil || got.AsNumber() != 7 {
+		t.Fatalf("read-only objects can be read. got=%v err=%v", got, err)
+	}
+
+	allowed, _ := New(o, Options{Allow: []string{"total"}})
+	if err := allowed.Set("total", value.Number(3)); err != nil || o.Total != 3 {
+		t.Fatalf("allowed property must be assignable. got=%v", err)
+	}
+	if _, err := allowed.Get("id"); err == nil {
+		t.Fatalf("properties not in the allowlist must be undefined")
+	}
+	if _, err := allowed.Get("addItem"); err == nil {
+		t.Fatalf("methods not in the allowlist must be undefined")
+	}
+
+	copied, _ := New(order{ID: 1}, Options{})
+	if err := copied.Set("id", value.Number(2)); err != nil {
+		t.Fatalf("a copied struct can be assigned. got=%v", err)
+	}
+}
+
+func get(obj *Object, name string) error {
+	_, err := obj.Get(name)
+	return err
+}
+
+func call(fn interpreter.Callable, args ...value.Value) error {
+	_, err := fn.Call(nil, args)
+	return err
+}
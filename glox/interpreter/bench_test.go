@@ -0,0 +1,58 @@
+package interpreter
+
+import (
+	"glox/lexer"
+	"glox/parser"
+	"io"
+	"testing"
+)
+
+// There is no `return` statement yet, so fib counts the leaves of its call
+// tree into a global instead: it still makes the fib(25) number of calls.
+const fibSrc = `
+let total = 0;
+fun fib(n) {
+	if (n < 2) {
+		total = total + n;
+	} else {
+		fib(n - 1);
+		fib(n - 2);
+	}
+}
+fib(25);
+`
+
+const nestedLoopsSrc = `
+let sum = 0;
+for (let i = 0; i < 300; i = i + 1) {
+	for (let j = 0; j < 300; j = j + 1) {
+		let k = i * j;
+		sum = sum + k;
+	}
+}
+`
+
+func BenchmarkFib(b *testing.B) {
+	benchmarkProgram(b, fibSrc)
+}
+
+func BenchmarkNestedLoops(b *testing.B) {
+	benchmarkProgram(b, nestedLoopsSrc)
+}
+
+func benchmarkProgram(b *testing.B, src string) {
+	tokens, err := lexer.New(src).Tokenize()
+	if err != nil {
+		b.Fatalf("failed to tokenize benchmark program. got='%s'", err.Error())
+	}
+	stmts, err := parser.New(tokens).Parse()
+	if err != nil {
+		b.Fatalf("failed to parse benchmark program. got='%s'", err.Error())
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		New(io.Discard, io.Discard).Interpret(stmts)
+	}
+}
@@ -1,7 +1,25 @@
 package interpreter
 
+import "glox/value"
+
 type Callable interface {
-	Call(i *Interpreter, arguments []any) any
+	Call(i *Interpreter, arguments []value.Value) (value.Value, error)
 	Arity() int
 	String() string
 }
+
+// Object is a value with properties, read with `object.name` and written with `object.name = value`. Its
+// errors are reported where the property is accessed.
+type Object interface {
+	Get(name string) (value.Value, error)
+	Set(name string, val value.Value) error
+	String() string
+}
+
+// Importer loads the modules imported by programs. A module is an object whose properties are the names it
+// exports.
+type Importer interface {
+	// Import returns the module at path as imported by the program from is running, see `Interpreter.Source`.
+	// The module runs within the budget of that program, see `Interpreter.EvalWithin`.
+	Import(from *Interpreter, path string) (Object, error)
+}
@@ -0,0 +1,47 @@
+package interpreter
+
+import (
+	"fmt"
+	"glox/exception"
+	"glox/value"
+)
+
+// The cause of the exception raised by `throw`, it carries the thrown value to the catch block.
+type thrown struct {
+	val value.Value
+}
+
+func (t *thrown) Error() string {
+	return t.val.String()
+}
+
+// caught is the exception bound by `catch (e)`. Its properties are `message`, `line`, `kind` (e.g.
+// "RuntimeException", "Exception" when it was thrown by the script) and `value`, the thrown value or nil.
+type caught struct {
+	err *exception.Error
+}
+
+func (c *caught) Get(name string) (value.Value, error) {
+	switch name {
+	case "message":
+		return value.String(c.err.Reason), nil
+	case "line":
+		return value.Number(float64(c.err.Line)), nil
+	case "kind":
+		return value.String(c.err.Kind), nil
+	case "value":
+		if t, isThrown := c.err.Cause.(*thrown); isThrown {
+			return t.val, nil
+		}
+		return value.Nil, nil
+	}
+	return value.Nil, fmt.Errorf("undefined property '%s'.", name)
+}
+
+func (c *caught) Set(name string, val value.Value) error {
+	return fmt.Errorf("property '%s' is read-only.", name)
+}
+
+func (c *caught) String() string {
+	return c.err.Message
+}
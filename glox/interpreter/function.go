@@ -4,24 +4,49 @@ import (
 	"fmt"
 	"glox/ast"
 	"glox/env"
+	"glox/exception"
+	"glox/token"
+	"glox/value"
 )
 
 type LoxFunction struct {
 	declaration *ast.Function
+	closure     *env.Environment
+	// Globals and name of the program that defined the function, e.g. a module. The function reads its
+	// globals there wherever it is called from and its errors are located in that program.
+	globals *env.Environment
+	source  string
 }
 
-func NewFunction(declaration *ast.Function) *LoxFunction {
-	return &LoxFunction{declaration: declaration}
+func NewFunction(declaration *ast.Function, closure *env.Environment, globals *env.Environment, source string) *LoxFunction {
+	return &LoxFunction{declaration: declaration, closure: closure, globals: globals, source: source}
 }
 
-func (fn *LoxFunction) Call(i *Interpreter, args []any) any {
-	env := env.New(i.Env)
-	for i, param := range fn.declaration.Params {
-		arg := args[i]
-		env.Define(param.Lexeme, arg)
+// A call made by `defer`, see `Interpreter.VisitDefer`.
+type deferredCall struct {
+	paren    token.Token
+	function Callable
+	args     []value.Value
+}
+
+func (fn *LoxFunction) Call(i *Interpreter, args []value.Value) (res value.Value, err error) {
+	env := env.New(fn.closure, fn.declaration.Locals)
+	// Parameters take the first slots of the call's environment.
+	for slot := range fn.declaration.Params {
+		env.Set(slot, args[slot])
 	}
-	i.executeBlock(fn.declaration.Body, env)
-	return nil
+
+	prev, deferred := i.deferred, []deferredCall{}
+	prevGlobals, prevSource := i.globals, i.Source
+	i.deferred, i.globals, i.Source = &deferred, fn.globals, fn.source
+	// Deferred calls also run when `break` or `continue` unwind the stack, by then the body has restored the
+	// environment of the caller.
+	defer func() {
+		i.deferred = prev
+		err = exception.Locate(i.runDeferred(deferred, err), fn.source)
+		i.globals, i.Source = prevGlobals, prevSource
+	}()
+	return value.Nil, i.executeBlock(fn.declaration.Body, env)
 }
 
 func (fn *LoxFunction) Arity() int {
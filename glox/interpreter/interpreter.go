@@ -1,423 +1,712 @@
 package interpreter
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"glox/ast"
+	"glox/capability"
 	"glox/env"
 	"glox/exception"
 	"glox/native"
+	"glox/resolver"
 	"glox/token"
+	"glox/value"
 	"io"
-	"math"
+	"time"
 )
 
+// Limits bound the resources used by a call to `Eval` or `Call`, zero means no limit. Going over a limit
+// stops the program with a LimitException.
+type Limits struct {
+	Statements int           // Number of statements executed.
+	Duration   time.Duration // Wall time.
+	CallDepth  int           // Number of nested function calls.
+}
+
+// Interpreter runs programs, the variables they define are kept from one program to the next. It must not be
+// used by several goroutines at the same time.
 type Interpreter struct {
 	StdOut io.Writer
 	StdErr io.Writer
-	Env    *env.Environment
+	Source string // Name of the program being interpreted, errors are reported with it when set.
+	Quiet  bool   // Expression statements do not print their value, e.g. when Lox is embedded.
+	Limits Limits
+	// What the program may do outside the interpreter, e.g. reading files. Nothing is allowed by default.
+	Capabilities capability.Set
+	Importer     Importer // Loads the modules of `import` statements, which fail when it is nil.
+	Env          *env.Environment
+	globals      *env.Environment
+	deferred     *[]deferredCall // Calls deferred by the running Lox function, nil outside of functions.
+
+	budget *budget // Of the running program, nil when the interpreter is not running.
+}
+
+// What a running program used of its limits, see `run`. The modules it imports count against it too.
+type budget struct {
+	ctx   context.Context
+	done  <-chan struct{}
+	steps int
+	depth int
 }
 
 func New(stderr io.Writer, stdout io.Writer) *Interpreter {
 	globals := env.Global()
-	globals.Define("clock", native.Clock[*Interpreter]())
-	return &Interpreter{StdOut: stdout, StdErr: stderr, Env: globals}
+	globals.Define("clock", value.Object(native.Clock[*Interpreter]()))
+	globals.Define("readFile", value.Object(native.ReadFile[*Interpreter]()))
+	globals.Define("writeFile", value.Object(native.WriteFile[*Interpreter]()))
+	globals.Define("getenv", value.Object(native.Getenv[*Interpreter]()))
+	globals.Define("exec", value.Object(native.Exec[*Interpreter]()))
+	return &Interpreter{StdOut: stdout, StdErr: stderr, Env: globals, globals: globals}
 }
 
-func (i *Interpreter) Interpret(stmts []ast.Statement) any {
-	var err error
-	for _, stmt := range stmts {
-		err = i.execute(stmt)
+// Check is called by the natives that access the system, it fails unless the capability is granted.
+func (i *Interpreter) Check(kind capability.Kind, resource string) error {
+	return i.Capabilities.Check(kind, resource)
+}
+
+// Context is the one of the running program, it is done once the program must stop.
+func (i *Interpreter) Context() context.Context {
+	if i.budget == nil {
+		return context.Background()
 	}
-	return err
+	return i.budget.ctx
 }
 
-// If an error occurs we both return it and pass it to the stderr. Ideally we would like only to pass it
-// to the stderr bur cause this method can be used by other methods, we will to inform them that an error
-// occured, thus returning the error also.
-func (i *Interpreter) execute(stmt ast.Statement) error {
-	val := stmt.Accept(i)
-	if err, isErr := val.(error); isErr {
+// Interpret runs a program until its end or until a statement fails. The error is reported to the stderr
+// before being returned.
+func (i *Interpreter) Interpret(stmts []ast.Statement) error {
+	if _, err := i.Eval(context.Background(), stmts); err != nil {
 		fmt.Fprintf(i.StdErr, "%s\n", err.Error())
 		return err
 	}
 	return nil
 }
 
-func (i *Interpreter) VisitLetStmt(stmt *ast.LetStmt) any {
-	var val any
-	if stmt.Value != nil {
-		val = i.evaluate(stmt.Value)
+// Eval runs a program like `Interpret` but leaves reporting the error to the caller. It returns the value of
+// the last statement when it is an expression, nil otherwise. The program is stopped once ctx is done.
+func (i *Interpreter) Eval(ctx context.Context, stmts []ast.Statement) (value.Value, error) {
+	resolver.New().Resolve(stmts)
+	return i.EvalResolved(ctx, stmts)
+}
+
+// EvalResolved runs a program that was already resolved. The program is only read, so that interpreters in
+// different goroutines can run it at the same time.
+func (i *Interpreter) EvalResolved(ctx context.Context, stmts []ast.Statement) (value.Value, error) {
+	result := value.Nil
+	err := i.run(ctx, func() error {
+		for _, stmt := range stmts {
+			var err error
+			if exp, isExp := stmt.(*ast.ExpressionStmt); isExp {
+				if err = i.tick(); err == nil {
+					result, err = i.expression(exp)
+				}
+			} else {
+				result, err = value.Nil, i.execute(stmt)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return value.Nil, exception.Locate(err, i.Source)
+	}
+	return result, nil
+}
+
+// EvalWithin runs a resolved program like `EvalResolved`, within the budget of the program parent is running.
+// It runs the modules parent imports: their statements and calls count against the limits of the importer
+// and they stop with its context.
+func (i *Interpreter) EvalWithin(parent *Interpreter, stmts []ast.Statement) (value.Value, error) {
+	if parent.budget == nil {
+		return i.EvalResolved(context.Background(), stmts)
+	}
+	i.budget = parent.budget
+	defer func() { i.budget = nil }()
+	return i.EvalResolved(parent.budget.ctx, stmts)
+}
+
+// Runs f within the limits of the interpreter and ctx. When the interpreter is already running, e.g. a native
+// function calls back into Lox, f shares the budget of the running program.
+func (i *Interpreter) run(ctx context.Context, f func() error) error {
+	if i.budget != nil {
+		return f()
+	}
+	if i.Limits.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, i.Limits.Duration)
+		defer cancel()
+	}
+	i.budget = &budget{ctx: ctx, done: ctx.Done()}
+	defer func() { i.budget = nil }()
+	return f()
+}
+
+// Counts a statement against the budget of the program.
+func (i *Interpreter) tick() error {
+	i.budget.steps++
+	if i.Limits.Statements > 0 && i.budget.steps > i.Limits.Statements {
+		return exception.Limit(token.Token{}, exception.ErrStatementLimit)
+	}
+	select {
+	case <-i.budget.done:
+		return exception.Limit(token.Token{}, i.budget.ctx.Err())
+	default:
+		return nil
+	}
+}
+
+// Call calls a function on behalf of Go code, e.g. a Lox function a script registered as an event handler.
+// It may be used while a program is running, from a native function, the environment of the running code is
+// restored when it returns.
+func (i *Interpreter) Call(ctx context.Context, fn Callable, args []value.Value) (value.Value, error) {
+	if fn.Arity() != len(args) {
+		return value.Nil, fmt.Errorf("%s expects %d arguments but got %d.", fn, fn.Arity(), len(args))
 	}
-	if err, isErr := val.(error); isErr {
+
+	prev := i.Env
+	defer func() { i.Env = prev }()
+	var res value.Value
+	err := i.run(ctx, func() (err error) {
+		res, err = fn.Call(i, args)
 		return err
+	})
+	if err != nil {
+		return value.Nil, exception.Locate(err, i.Source)
 	}
+	return res, nil
+}
 
-	i.Env.Define(stmt.Name.Lexeme, val)
+// Errors abort every enclosing statement up to `Interpret`, which reports them.
+func (i *Interpreter) execute(stmt ast.Statement) error {
+	if err := i.tick(); err != nil {
+		return err
+	}
+	if err, isErr := stmt.Accept(i).(error); isErr {
+		return err
+	}
+	return nil
+}
+
+func (i *Interpreter) VisitLetStmt(stmt *ast.LetStmt) any {
+	val := value.Nil
+	if stmt.Value != nil {
+		var err error
+		if val, err = i.evaluate(stmt.Value); err != nil {
+			return err
+		}
+	}
+
+	i.define(stmt.Name.Lexeme, stmt.Binding, val)
 	return nil
 }
 
 func (i *Interpreter) VisitIfStmt(stmt *ast.IfStmt) any {
-	if isTruthy(i.evaluate(stmt.Condition)) {
-		i.execute(stmt.Then)
+	cond, err := i.evaluate(stmt.Condition)
+	if err != nil {
+		return err
+	}
+	if cond.Truthy() {
+		return i.execute(stmt.Then)
 	} else if stmt.OrElse != nil {
-		i.execute(stmt.OrElse)
+		return i.execute(stmt.OrElse)
 	}
 
 	return nil
 }
 
 func (i *Interpreter) VisitExprStmt(stmt *ast.ExpressionStmt) any {
-	val := i.evaluate(stmt.Exp)
-	if err, isErr := val.(error); isErr {
+	if _, err := i.expression(stmt); err != nil {
 		return err
 	}
-	fmt.Fprintf(i.StdOut, "%v\n", val)
 	return nil
 }
 
+func (i *Interpreter) expression(stmt *ast.ExpressionStmt) (value.Value, error) {
+	val, err := i.evaluate(stmt.Exp)
+	if err == nil && !i.Quiet {
+		fmt.Fprintln(i.StdOut, val.String())
+	}
+	return val, err
+}
+
 func (i *Interpreter) VisitPrintStmt(stmt *ast.PrintStmt) any {
-	val := i.evaluate(stmt.Exp)
-	if err, isErr := val.(error); isErr {
-		fmt.Fprintf(i.StdErr, "%s\n", err.Error())
-	} else {
-		fmt.Fprintf(i.StdOut, "%v\n", val)
+	val, err := i.evaluate(stmt.Exp)
+	if err != nil {
+		return err
 	}
+	fmt.Fprintln(i.StdOut, val.String())
 	return nil
 }
 
 func (i *Interpreter) VisitBlockStmt(stmt *ast.BlockStmt) any {
-	i.executeBlock(stmt.Stmts, env.New(i.Env))
-	return nil
+	return i.executeBlock(stmt.Stmts, env.New(i.Env, stmt.Locals))
 }
 
-func (i *Interpreter) executeBlock(stmts []ast.Statement, env *env.Environment) {
+func (i *Interpreter) executeBlock(stmts []ast.Statement, env *env.Environment) error {
 	prev := i.Env
 	i.Env = env
+	// `break` and `continue` unwind the stack with a panic, the previous environment must be restored anyway.
+	defer func() { i.Env = prev }()
 	for _, stmt := range stmts {
-		i.execute(stmt)
+		if err := i.execute(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (i *Interpreter) VisitBranch(stmt *ast.BranchStmt) any {
+	panic(stmt)
+}
+
+func (i *Interpreter) VisitWhile(exp *ast.WhileStmt) any {
+
+	cond, err := i.evaluate(exp.Condition)
+
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Consumes panic(errBreak)
+		if r := recover(); r != nil {
+			if branch, isBranch := r.(*ast.BranchStmt); !isBranch || branch.Token.Type != token.BREAK {
+				panic(r)
+			}
+		}
+	}()
+	for cond.Truthy() {
+		if cond, err = i.execLoop(exp); err != nil {
+			return err
+		}
 	}
-	i.Env = prev
+
+	return nil
+}
+
+func (i *Interpreter) VisitFunction(stmt *ast.Function) any {
+	fn := NewFunction(stmt, i.Env, i.globals, i.Source)
+	i.define(stmt.Name.Lexeme, stmt.Binding, value.Object(fn))
+	return nil
 }
 
-func (i *Interpreter) VisitVariable(exp *ast.Variable) any {
-	if res := i.Env.Get(exp.Name); res != nil {
-		return res
+func (i *Interpreter) VisitImport(stmt *ast.ImportStmt) any {
+	if i.Importer == nil {
+		return exception.Runtime(stmt.Keyword, "modules cannot be imported here.")
+	}
+	module, err := i.Importer.Import(i, stmt.Path.Literal.(string))
+	if err != nil {
+		// Errors raised by the module's code are already located in it.
+		if _, isException := err.(*exception.Error); isException {
+			return err
+		}
+		return exception.Wrap(stmt.Keyword, err)
 	}
 
-	return exception.Runtime(exp.Name, fmt.Sprintf("tried to access variable '%s' which holds a nil value.", exp.Name.Lexeme))
+	if len(stmt.Names) == 0 {
+		i.define(stmt.Alias.Lexeme, stmt.Bindings[0], value.Object(module))
+		return nil
+	}
+	for n, name := range stmt.Names {
+		val, err := module.Get(name.Lexeme)
+		if err != nil {
+			return exception.Wrap(name, err)
+		}
+		i.define(name.Lexeme, stmt.Bindings[n], val)
+	}
+	return nil
 }
 
-func (i *Interpreter) VisitLiteral(exp *ast.Literal) any {
-	return exp.Value
+func (i *Interpreter) VisitThrow(stmt *ast.ThrowStmt) any {
+	val, err := i.evaluate(stmt.Value)
+	if err != nil {
+		return err
+	}
+	// `throw e;` in a catch block raises the caught exception again.
+	if c, isCaught := val.AsObject().(*caught); isCaught {
+		return c.err
+	}
+	return exception.Throw(stmt.Keyword, val.String(), &thrown{val: val})
 }
 
-func (i Interpreter) VisitGrouping(exp *ast.Grouping) any {
-	return i.evaluate(exp.Exp)
+func (i *Interpreter) VisitTry(stmt *ast.TryStmt) (res any) {
+	if stmt.Finally == nil {
+		return i.catch(stmt)
+	}
+
+	unwinding := true
+	defer func() {
+		// `break` and `continue` leave the try statement with a panic, the finally block runs anyway.
+		if !unwinding {
+			return
+		}
+		r := recover()
+		if err := i.execute(stmt.Finally); err != nil {
+			res = err
+			return
+		}
+		panic(r)
+	}()
+	err := i.catch(stmt)
+	unwinding = false
+
+	// A program over its limits stops right away.
+	if exception.IsLimit(err) {
+		return err
+	}
+	if finallyErr := i.execute(stmt.Finally); finallyErr != nil {
+		return finallyErr
+	}
+	return err
 }
 
-func (i *Interpreter) VisitUnary(exp *ast.Unary) any {
-	right := i.evaluate(exp.Right)
+// Runs the body of a try statement and its catch block when the body raises an exception. Exceptions raised
+// because the program went over its limits cannot be caught.
+func (i *Interpreter) catch(stmt *ast.TryStmt) error {
+	err := i.execute(stmt.Body)
+	var e *exception.Error
+	if err == nil || stmt.Catch == nil || exception.IsLimit(err) || !errors.As(err, &e) {
+		return err
+	}
+	env := env.New(i.Env, stmt.Catch.Locals)
+	env.Set(0, value.Object(&caught{err: e}))
+	return i.executeBlock(stmt.Catch.Stmts, env)
+}
+
+// The function and its arguments are evaluated right away, the call is made when the function returns.
+func (i *Interpreter) VisitDefer(stmt *ast.DeferStmt) any {
+	if i.deferred == nil {
+		return exception.Runtime(stmt.Keyword, "'defer' cannot be used outside of a function.")
+	}
+	function, args, err := i.callee(stmt.Call)
+	if err != nil {
+		return err
+	}
+	*i.deferred = append(*i.deferred, deferredCall{paren: stmt.Call.Paren, function: function, args: args})
+	return nil
+}
+
+// Runs the calls deferred by a function, the last one first. They all run even when some fail, err is the
+// error the function failed with and it is kept over theirs. Nothing runs once the program is over its limits.
+func (i *Interpreter) runDeferred(calls []deferredCall, err error) error {
+	for n := len(calls) - 1; n >= 0 && !exception.IsLimit(err); n-- {
+		if _, callErr := i.invoke(calls[n].paren, calls[n].function, calls[n].args); callErr != nil && (err == nil || exception.IsLimit(callErr)) {
+			err = callErr
+		}
+	}
+	return err
+}
+
+func (i *Interpreter) execLoop(loop *ast.WhileStmt) (res value.Value, err error) {
+	//FIXME: The `continue` statement doesn't seem to work as expected.
+	defer func() {
+		// Consumes panic(errContinue)
+		if r := recover(); r != nil {
+			if branch, isBranch := r.(*ast.BranchStmt); !isBranch || branch.Token.Type != token.CONTINUE {
+				panic(r)
+			}
+		}
+	}()
+
+	if err = i.execute(loop.Body); err != nil {
+		return res, err
+	}
+	return i.evaluate(loop.Condition)
+}
+
+// Expressions are dispatched with a type switch instead of `Accept`: going through `ast.Visitor` would box
+// every intermediate value into an `any`.
+func (i *Interpreter) evaluate(exp ast.Expression) (value.Value, error) {
+	switch exp := exp.(type) {
+	case *ast.Literal:
+		return value.Of(exp.Value), nil
+	case *ast.Variable:
+		return i.variable(exp)
+	case *ast.Binary:
+		return i.binary(exp)
+	case *ast.Unary:
+		return i.unary(exp)
+	case *ast.Grouping:
+		return i.evaluate(exp.Exp)
+	case *ast.Ternary:
+		return i.ternary(exp)
+	case *ast.Assignment:
+		return i.assignment(exp)
+	case *ast.Logical:
+		return i.logical(exp)
+	case *ast.Call:
+		return i.call(exp)
+	case *ast.Get:
+		return i.get(exp)
+	case *ast.Set:
+		return i.set(exp)
+	}
+
+	panic(fmt.Sprintf("%T cannot be evaluated.", exp))
+}
+
+func (i *Interpreter) variable(exp *ast.Variable) (value.Value, error) {
+	res, err := i.lookup(exp.Name, exp.Binding)
+	if err != nil || !res.IsNil() {
+		return res, err
+	}
+
+	return res, exception.Runtime(exp.Name, fmt.Sprintf("tried to access variable '%s' which holds a nil value.", exp.Name.Lexeme))
+}
+
+func (i *Interpreter) unary(exp *ast.Unary) (value.Value, error) {
+	right, err := i.evaluate(exp.Right)
+	if err != nil {
+		return right, err
+	}
 
 	switch exp.Operator.Type {
 	case token.BANG:
-		return !isTruthy(right)
+		return value.Bool(!right.Truthy()), nil
 	case token.MINUS:
 		num, err := checkOperand(exp.Operator, right)
 		if err != nil {
-			return err
+			return value.Nil, err
 		}
-		return -*num
+		return value.Number(-num), nil
 
 	}
 
-	return nil
+	return value.Nil, nil
 }
 
-func (i *Interpreter) VisitBinary(exp *ast.Binary) any {
-	left := i.evaluate(exp.Left)
-	right := i.evaluate(exp.Right)
-
-	lErr, isLErr := right.(error)
-	rErr, isRErr := left.(error)
-	if isLErr && !isRErr {
-		return lErr
-	} else if isRErr && !isLErr {
-		return rErr
+func (i *Interpreter) binary(exp *ast.Binary) (value.Value, error) {
+	left, err := i.evaluate(exp.Left)
+	if err != nil {
+		return left, err
+	}
+	right, err := i.evaluate(exp.Right)
+	if err != nil {
+		return right, err
 	}
 
 	switch exp.Operator.Type {
 	case token.GREATER:
-		leftNum, err := checkOperand(exp.Operator, left)
-		if err != nil {
-			return err
-		}
-		rightNum, err := checkOperand(exp.Operator, right)
-		if err != nil {
-			return err
-		}
-		return *leftNum > *rightNum
+		leftNum, rightNum, err := checkOperands(exp.Operator, left, right)
+		return value.Bool(leftNum > rightNum), err
 	case token.GREATER_EQ:
-		leftNum, err := checkOperand(exp.Operator, left)
-		if err != nil {
-			return err
-		}
-		rightNum, err := checkOperand(exp.Operator, right)
-		if err != nil {
-			return err
-		}
-		return *leftNum >= *rightNum
+		leftNum, rightNum, err := checkOperands(exp.Operator, left, right)
+		return value.Bool(leftNum >= rightNum), err
 	case token.LESS:
-		leftNum, err := checkOperand(exp.Operator, left)
-		if err != nil {
-			return err
-		}
-		rightNum, err := checkOperand(exp.Operator, right)
-		if err != nil {
-			return err
-		}
-		return *leftNum < *rightNum
+		leftNum, rightNum, err := checkOperands(exp.Operator, left, right)
+		return value.Bool(leftNum < rightNum), err
 	case token.LESS_EQ:
-		leftNum, err := checkOperand(exp.Operator, left)
-		if err != nil {
-			return err
-		}
-		rightNum, err := checkOperand(exp.Operator, right)
-		if err != nil {
-			return err
-		}
-		return *leftNum <= *rightNum
+		leftNum, rightNum, err := checkOperands(exp.Operator, left, right)
+		return value.Bool(leftNum <= rightNum), err
 	case token.EQ_EQ:
-		return isEqual(left, right)
+		return value.Bool(value.Equal(left, right)), nil
 	case token.BANG_EQ:
-		return !isEqual(left, right)
+		return value.Bool(!value.Equal(left, right)), nil
 	case token.MINUS:
-		leftNum, err := checkOperand(exp.Operator, left)
-		if err != nil {
-			return err
-		}
-		rightNum, err := checkOperand(exp.Operator, right)
-		if err != nil {
-			return err
-		}
-		return *leftNum - *rightNum
+		leftNum, rightNum, err := checkOperands(exp.Operator, left, right)
+		return value.Number(leftNum - rightNum), err
 	case token.PLUS:
-		if leftNum, isLFloat := left.(float64); isLFloat {
-			rightNum, isRFloat := right.(float64)
-			if isRFloat {
-				return leftNum + rightNum
-			} else if rightVal, isRightStr := right.(string); isRightStr {
-				return fmt.Sprintf("%v%s", leftNum, rightVal)
-			}
-		} else if leftVal, isLeftStr := left.(string); isLeftStr {
-			if rightVal, isRightStr := right.(string); isRightStr {
-				return leftVal + rightVal
-			} else if rightNum, isRightNum := right.(float64); isRightNum {
-				return fmt.Sprintf("%s%v", leftVal, rightNum)
-			}
+		if left.IsNumber() && right.IsNumber() {
+			return value.Number(left.AsNumber() + right.AsNumber()), nil
+		}
+		if (left.IsString() || left.IsNumber()) && (right.IsString() || right.IsNumber()) {
+			return value.String(left.String() + right.String()), nil
 		}
 
-		return exception.Runtime(exp.Operator, "unsupported operands. This operation can only be performed with numbers and strings.")
+		return value.Nil, exception.Runtime(exp.Operator, "unsupported operands. This operation can only be performed with numbers and strings.")
 
 	case token.SLASH:
-		leftNum, err := checkOperand(exp.Operator, left)
+		leftNum, rightNum, err := checkOperands(exp.Operator, left, right)
 		if err != nil {
-			return err
-		}
-		rightNum, err := checkOperand(exp.Operator, right)
-		if err != nil {
-			return err
+			return value.Nil, err
 		}
 
-		if *rightNum == 0 {
-			return exception.Runtime(exp.Operator, "division by zero")
+		if rightNum == 0 {
+			return value.Nil, exception.Runtime(exp.Operator, "division by zero")
 		}
-		return *leftNum / *rightNum
+		return value.Number(leftNum / rightNum), nil
 	case token.ASTERISK:
-		leftNum, err := checkOperand(exp.Operator, left)
-		if err != nil {
-			return err
-		}
-		rightNum, err := checkOperand(exp.Operator, right)
-		if err != nil {
-			return err
-		}
-		return *leftNum * *rightNum
+		leftNum, rightNum, err := checkOperands(exp.Operator, left, right)
+		return value.Number(leftNum * rightNum), err
 	}
 
-	return nil
+	return value.Nil, nil
 }
 
-func (i *Interpreter) VisitTernary(exp *ast.Ternary) any {
-	condition := i.evaluate(exp.Condition)
-	then := i.evaluate(exp.Then)
-	orElse := i.evaluate(exp.OrElse)
+func (i *Interpreter) ternary(exp *ast.Ternary) (value.Value, error) {
+	condition, err := i.evaluate(exp.Condition)
+	if err != nil {
+		return condition, err
+	}
 
-	if isTruthy(condition) {
-		return then
+	if condition.Truthy() {
+		return i.evaluate(exp.Then)
 	} else {
-		return orElse
+		return i.evaluate(exp.OrElse)
 	}
 
 }
 
-func (i *Interpreter) VisitAssignment(exp *ast.Assignment) any {
-	val := i.evaluate(exp.Value)
-	if err, isErr := val.(error); isErr {
-		return err
+func (i *Interpreter) assignment(exp *ast.Assignment) (value.Value, error) {
+	val, err := i.evaluate(exp.Value)
+	if err != nil {
+		return val, err
 	}
-	if err := i.Env.Assign(exp.Name, val); err != nil {
-		return err
+	if exp.Binding.Local {
+		i.Env.AssignAt(exp.Binding.Depth, exp.Binding.Slot, val)
+	} else if err := i.globals.Assign(exp.Name, val); err != nil {
+		return val, err
 	}
-	return val
+	return val, nil
 }
 
-func (i *Interpreter) VisitLogical(exp *ast.Logical) any {
-	left := i.evaluate(exp.Left)
-	if err, isErr := left.(error); isErr {
-		return err
+func (i *Interpreter) logical(exp *ast.Logical) (value.Value, error) {
+	left, err := i.evaluate(exp.Left)
+	if err != nil {
+		return left, err
 	}
 
 	if exp.Operator.Type == token.OR {
-		if isTruthy(left) {
-			return left
+		if left.Truthy() {
+			return left, nil
 		}
 	} else {
-		if !isTruthy(left) {
-			return left
+		if !left.Truthy() {
+			return left, nil
 		}
 	}
 
 	return i.evaluate(exp.Right)
 }
 
-func (i *Interpreter) VisitBranch(stmt *ast.BranchStmt) any {
-	panic(stmt)
+func (i *Interpreter) call(expr *ast.Call) (value.Value, error) {
+	function, args, err := i.callee(expr)
+	if err != nil {
+		return value.Nil, err
+	}
+	return i.invoke(expr.Paren, function, args)
 }
 
-func (i *Interpreter) VisitWhile(exp *ast.WhileStmt) any {
-
-	cond := i.evaluate(exp.Condition)
-
-	if err, isErr := cond.(error); isErr {
-		return err
+// Evaluates the function and the arguments of a call, which is ready to be invoked once they match.
+func (i *Interpreter) callee(expr *ast.Call) (Callable, []value.Value, error) {
+	calle, err := i.evaluate(expr.Callee)
+	if err != nil {
+		return nil, nil, err
 	}
-	for isTruthy(cond) {
-		defer func() {
-			// Consumes panic(errBreak)
-			if r := recover(); r != nil {
-				if branch, isBranch := r.(*ast.BranchStmt); !isBranch || branch.Token.Type != token.BREAK {
-					panic(r)
-				}
-			}
-		}()
-		res, err := i.execLoop(exp)
+	args := make([]value.Value, 0, len(expr.Args))
+	for _, arg := range expr.Args {
+		val, err := i.evaluate(arg)
 		if err != nil {
-			// The error is already handled by `execute` so there is no need to
-			// return it.
-			break
+			return nil, nil, err
 		}
-		cond = res
+		args = append(args, val)
 	}
-
-	return nil
-}
-
-func (i *Interpreter) VisitCall(expr *ast.Call) any {
-	calle := i.evaluate(expr.Callee)
-	err, isErr := calle.(error)
-	if !isErr {
-		args := []any{}
-		for _, arg := range expr.Args {
-			val := i.evaluate(arg)
-			if err, isErr = val.(error); isErr {
-				break
-			}
-			args = append(args, val)
+	function, isOk := calle.AsObject().(Callable)
+	if !isOk {
+		return nil, nil, exception.Runtime(expr.Paren, fmt.Sprintf("'%v' cannot be called.", expr.Callee.String()))
+	} else if function.Arity() != len(args) {
+		want, got := function.Arity(), len(args)
+		var msg string
+		if got > want {
+			msg = fmt.Sprintf("too many arguments passed. expected %d but got %d.", want, got)
+		} else {
+			msg = fmt.Sprintf("not enough arguments passed. expected %d but got %d.", want, got)
 		}
-		if err != nil {
-			return err
-		}
-		function, isOk := calle.(Callable)
-		if !isOk {
-			return exception.Runtime(expr.Paren, fmt.Sprintf("'%v' cannot be called.", expr.Callee.String()))
-		} else if function.Arity() != len(args) {
-			want, got := function.Arity(), len(args)
-			var msg string
-			if got > want {
-				msg = fmt.Sprintf("too many arguments passed. expected %d but got %d.", want, got)
-			} else {
-				msg = fmt.Sprintf("not enough arguments passed. expected %d but got %d.", want, got)
-			}
-			return exception.Runtime(expr.Paren, msg)
-		}
-		return function.Call(i, args)
+		return nil, nil, exception.Runtime(expr.Paren, msg)
 	}
-
-	return err
-
-}
-
-func (i *Interpreter) VisitFunction(stmt *ast.Function) any {
-	fn := NewFunction(stmt)
-	i.Env.Define(stmt.Name.Lexeme, fn)
-	return nil
+	return function, args, nil
 }
 
-func (i *Interpreter) execLoop(loop *ast.WhileStmt) (res any, err error) {
-	//FIXME: The `continue` statement doesn't seem to work as expected.
-	defer func() {
-		// Consumes panic(errContinue)
-		if r := recover(); r != nil {
-			if branch, isBranch := r.(*ast.BranchStmt); !isBranch || branch.Token.Type != token.CONTINUE {
-				panic(r)
-			}
+func (i *Interpreter) invoke(paren token.Token, function Callable, args []value.Value) (value.Value, error) {
+	i.budget.depth++
+	if i.Limits.CallDepth > 0 && i.budget.depth > i.Limits.CallDepth {
+		i.budget.depth--
+		return value.Nil, exception.Limit(paren, exception.ErrCallDepthLimit)
+	}
+	res, err := function.Call(i, args)
+	i.budget.depth--
+	if _, isLox := function.(*LoxFunction); err != nil && !isLox && !exception.IsLimit(err) {
+		// A native stopped by the context of the program, e.g. `exec()`, stops it like going over a limit does.
+		if cause := i.budget.ctx.Err(); cause != nil {
+			return value.Nil, exception.Limit(paren, cause)
 		}
-	}()
-
-	err = i.execute(loop.Body)
-	res = i.evaluate(loop.Condition)
+		// Natives know nothing about the source, their errors are reported where they were called.
+		return value.Nil, exception.Wrap(paren, err)
+	}
 	return res, err
 }
 
-func (i *Interpreter) evaluate(exp ast.Expression) any {
-	return exp.Accept(i)
+func (i *Interpreter) get(exp *ast.Get) (value.Value, error) {
+	object, err := i.object(exp.Object, exp.Name)
+	if err != nil {
+		return value.Nil, err
+	}
+	val, err := object.Get(exp.Name.Lexeme)
+	if err != nil {
+		return value.Nil, exception.Wrap(exp.Name, err)
+	}
+	return val, nil
 }
 
-// Only `nil` and `false` are falsey, everything else is truthy.
-func isTruthy(object any) bool {
-	if object == nil {
-		return false
+func (i *Interpreter) set(exp *ast.Set) (value.Value, error) {
+	object, err := i.object(exp.Object, exp.Name)
+	if err != nil {
+		return value.Nil, err
 	}
-
-	if val, isBool := object.(bool); isBool {
-		return val
+	val, err := i.evaluate(exp.Value)
+	if err != nil {
+		return value.Nil, err
 	}
-
-	return true
+	if err := object.Set(exp.Name.Lexeme, val); err != nil {
+		return value.Nil, exception.Wrap(exp.Name, err)
+	}
+	return val, nil
 }
 
-func isEqual(l any, r any) bool {
-	lNum, isLOk := l.(float64)
-	rNum, isROk := r.(float64)
-	if isLOk && isROk {
-		if math.IsNaN(lNum) && math.IsNaN(rNum) {
-			return true
-		}
+func (i *Interpreter) object(exp ast.Expression, name token.Token) (Object, error) {
+	val, err := i.evaluate(exp)
+	if err != nil {
+		return nil, err
+	}
+	if object, isObject := val.AsObject().(Object); isObject {
+		return object, nil
+	}
+	return nil, exception.Runtime(name, fmt.Sprintf("cannot access property '%s' of %s '%s'.", name.Lexeme, val.Kind(), val))
+}
 
-		return lNum == rNum
+func (i *Interpreter) define(name string, binding ast.Binding, val value.Value) {
+	if binding.Local {
+		i.Env.Set(binding.Slot, val)
+	} else {
+		i.Env.Define(name, val)
 	}
+}
 
-	return l == r
+func (i *Interpreter) lookup(name token.Token, binding ast.Binding) (value.Value, error) {
+	if binding.Local {
+		return i.Env.GetAt(binding.Depth, binding.Slot), nil
+	}
+	return i.globals.Get(name)
 }
 
-func checkOperand(operator token.Token, operand any) (*float64, error) {
-	num, isNum := operand.(float64)
-	if !isNum {
-		return nil, exception.Runtime(
+func checkOperand(operator token.Token, operand value.Value) (float64, error) {
+	if !operand.IsNumber() {
+		return 0, exception.Runtime(
 			operator,
 			fmt.Sprintf("Operator %q only accepts number operands.", operator.Lexeme),
 		)
 	}
 
-	return &num, nil
+	return operand.AsNumber(), nil
+}
+
+func checkOperands(operator token.Token, left value.Value, right value.Value) (float64, float64, error) {
+	leftNum, err := checkOperand(operator, left)
+	if err != nil {
+		return 0, 0, err
+	}
+	rightNum, err := checkOperand(operator, right)
+	return leftNum, rightNum, err
 }
@@ -2,13 +2,21 @@ package interpreter
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"glox/capability"
+	"glox/exception"
 	"glox/lexer"
 	"glox/parser"
 	"glox/token"
+	"glox/value"
+	"io"
 	"math/rand"
+	"os/exec"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestInterpret(t *testing.T) {
@@ -48,6 +56,12 @@ func TestInterpret(t *testing.T) {
 		`let count=0; while(count<5){count=count+1;}`:                                              "1\n2\n3\n4\n5",
 		`fun greets(name){print "Hello "+name+"!";}greets("John");`:                                "Hello John!\n<nil>",
 		`fun count(n) {if(n > 1) count(n-1); print n;} count(5);`:                                  "1\n<nil>\n2\n<nil>\n3\n<nil>\n4\n<nil>\n5\n<nil>",
+		`let a = "global"; { fun show() { print a; } show(); let a = "local"; show(); }`:           "global\n<nil>\nglobal\n<nil>",
+		`let x = 1; { let x = x + 1; { let x = x * 10; print x; } print x; } print x;`:             "20\n2\n1",
+		`print clock() > 0;`:       "true",
+		`print true ? 1 : 1/0;`:    "1",
+		`print 1 + 2 + " apples";`: "3 apples",
+		"// comment\nprint /* inline */ 1; /* a /* nested */ comment */": "1",
 	}
 
 	for code, expected := range fixtures {
@@ -173,12 +187,9 @@ func TestInterpret(t *testing.T) {
 		}
 
 		tok := token.Token{Type: token.IDENTIFIER, Lexeme: variable.name, Literal: nil, Line: 1}
-		got := i.Env.Get(tok)
+		got, _ := i.Env.Get(tok)
 
-		expected := variable.value
-		if num, isOk := expected.(int); isOk {
-			expected = float64(num)
-		}
+		expected := value.Of(variable.value)
 
 		if got != expected {
 			t.Fatalf("failed to keep state of defined variable in code=%q. got='%v'\nexpected='%v'.", variable.code, got, expected)
@@ -222,3 +233,199 @@ func TestInterpret(t *testing.T) {
 	}
 
 }
+
+func TestRuntimeErrorStopsProgram(t *testing.T) {
+	tests := []struct {
+		code    string
+		wantOut string
+	}{
+		{`print "a"; { print 1/0; print "b"; } print "c";`, "a\n"},
+		{`fun fail() { print -"x"; print "b"; } print "a"; fail(); print "c";`, "a\n"},
+		{`while (true) { print "a"; if (true) print nope; } print "c";`, "a\n"},
+		{`let x = 1; print "a"; x.y = 2; print "c";`, "a\n"},
+		{`print "a"; print clock.name; print "c";`, "a\n"},
+	}
+
+	for _, test := range tests {
+		stdout, stderr := bytes.NewBufferString(""), bytes.NewBufferString("")
+		tokens, err := lexer.New(test.code).Tokenize()
+		if err != nil {
+			t.Fatalf("Scanning failed with exception='%v'", err.Error())
+		}
+		stmts, err := parser.New(tokens).Parse()
+		if err != nil {
+			t.Fatalf("failed to parse code %q. got=%v", test.code, err.Error())
+		}
+
+		err = New(stderr, stdout).Interpret(stmts)
+		if err == nil {
+			t.Fatalf("%q -> expected Interpret to return the runtime error.", test.code)
+		}
+		if stdout.String() != test.wantOut {
+			t.Fatalf("%q -> wrong output. want=%q got=%q", test.code, test.wantOut, stdout.String())
+		}
+		if stderr.String() != err.Error()+"\n" {
+			t.Fatalf("%q -> the error must be reported once. got=%q", test.code, stderr.String())
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		code   string
+		ctx    context.Context
+		limits Limits
+		want   error
+	}{
+		{`while (true) {}`, context.Background(), Limits{Statements: 1000}, exception.ErrStatementLimit},
+		{`while (true) {}`, context.Background(), Limits{Duration: 10 * time.Millisecond}, context.DeadlineExceeded},
+		{`print 1;`, cancelled, Limits{}, context.Canceled},
+		{`fun loop(n) { loop(n + 1); } loop(0);`, context.Background(), Limits{CallDepth: 50}, exception.ErrCallDepthLimit},
+		{`let x = 0; while (x < 10) { x = x + 1; }`, context.Background(), Limits{Statements: 100, CallDepth: 1}, nil},
+	}
+
+	for _, test := range tests {
+		tokens, _ := lexer.New(test.code).Tokenize()
+		stmts, err := parser.New(tokens).Parse()
+		if err != nil {
+			t.Fatalf("failed to parse code %q. got=%v", test.code, err.Error())
+		}
+
+		glox := New(io.Discard, io.Discard)
+		glox.Limits = test.limits
+		_, err = glox.Eval(test.ctx, stmts)
+		if !errors.Is(err, test.want) {
+			t.Fatalf("%q -> wrong error. want=%v got=%v", test.code, test.want, err)
+		}
+		if test.want != nil && !exception.IsLimit(err) {
+			t.Fatalf("%q -> expected a limit exception. got=%v", test.code, err)
+		}
+		if glox.Env != glox.globals {
+			t.Fatalf("%q -> the global environment must be restored", test.code)
+		}
+	}
+}
+
+func TestLimitsStopCommands(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep is not available")
+	}
+	tokens, _ := lexer.New(`try { exec("sleep 10"); } catch (e) { print "caught"; }`).Tokenize()
+	stmts, err := parser.New(tokens).Parse()
+	if err != nil {
+		t.Fatalf("failed to parse code. got=%v", err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	var stdout bytes.Buffer
+	glox := New(io.Discard, &stdout)
+	glox.Capabilities = capability.All()
+	start := time.Now()
+	_, err = glox.Eval(ctx, stmts)
+	if !errors.Is(err, context.Canceled) || !exception.IsLimit(err) {
+		t.Fatalf("cancelling the program must stop the command. got=%v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("the command kept running after the program was cancelled. took=%s", elapsed)
+	}
+	if stdout.Len() > 0 {
+		t.Fatalf("a cancelled program cannot catch it. got=%q", stdout.String())
+	}
+}
+
+func TestTryCatch(t *testing.T) {
+	tests := []struct {
+		code    string
+		wantOut string
+		wantErr string // Kind of the exception that stops the program, empty when it runs until its end.
+	}{
+		{`try { print 1/nope; } catch (e) { print e.kind; print e.message; print e.line; }`, "RuntimeException\nundefined variable 'nope'.\n1\n", ""},
+		{`try { throw "bad"; } catch (e) { print e.kind; print e.message; print e.value; }`, "Exception\nbad\nbad\n", ""},
+		{`try { throw 41; } catch (e) { print e.value + 1; }`, "42\n", ""},
+		{`fun fail() { throw "deep"; print "no"; } try { fail(); print "no"; } catch (e) { print e.message; }`, "deep\n", ""},
+		{`try { print "a"; } catch (e) { print "no"; } finally { print "b"; }`, "a\nb\n", ""},
+		{`try { throw "x"; } finally { print "cleanup"; }`, "cleanup\n", exception.THROWN_EXCEPTION},
+		{`try { throw "x"; } catch (e) { throw e; }`, "", exception.THROWN_EXCEPTION},
+		{`try { throw "x"; } catch (e) { print -e; }`, "", exception.RUNTIME_EXCEPTION},
+		{`try { throw "x"; } catch (e) { print "a"; } finally { print nope; }`, "a\n", exception.RUNTIME_EXCEPTION},
+		{`while (true) { try { break; } finally { print "left"; } } print "after";`, "left\nafter\n", ""},
+		{`let i = 0; while (i < 1) { i = i + 1; try { continue; } finally { print i; } } print "after";`, "1\nafter\n", ""},
+		{`let e = "outer"; try { throw 1; } catch (e) { e = 2; } print e;`, "outer\n", ""},
+		{`try { while (true) {} } catch (e) { print "caught"; } finally { print "finally"; }`, "", exception.LIMIT_EXCEPTION},
+	}
+
+	for _, test := range tests {
+		stdout := &bytes.Buffer{}
+		tokens, _ := lexer.New(test.code).Tokenize()
+		stmts, err := parser.New(tokens).Parse()
+		if err != nil {
+			t.Fatalf("failed to parse code %q. got=%v", test.code, err.Error())
+		}
+
+		glox := New(io.Discard, stdout)
+		glox.Quiet = true
+		glox.Limits = Limits{Statements: 1000}
+		_, err = glox.Eval(context.Background(), stmts)
+		var e *exception.Error
+		if test.wantErr == "" && err != nil {
+			t.Fatalf("%q -> unexpected error. got=%v", test.code, err)
+		} else if test.wantErr != "" && (!errors.As(err, &e) || e.Kind != test.wantErr) {
+			t.Fatalf("%q -> wrong error. want kind=%s got=%v", test.code, test.wantErr, err)
+		}
+		if stdout.String() != test.wantOut {
+			t.Fatalf("%q -> wrong output. want=%q got=%q", test.code, test.wantOut, stdout.String())
+		}
+		if glox.Env != glox.globals {
+			t.Fatalf("%q -> the global environment must be restored", test.code)
+		}
+	}
+}
+
+func TestDefer(t *testing.T) {
+	tests := []struct {
+		code    string
+		wantOut string
+		wantErr string // Kind of the exception that stops the program, empty when it runs until its end.
+	}{
+		{`fun log(m) { print m; } fun f() { defer log(1); defer log(2); print 3; } f(); print 4;`, "3\n2\n1\n4\n", ""},
+		{`fun log(m) { print m; } fun f() { let x = "before"; defer log(x); x = "after"; } f();`, "before\n", ""},
+		{`fun log(m) { print m; } fun f() { defer log("cleanup"); print nope; print "no"; } f();`, "cleanup\n", exception.RUNTIME_EXCEPTION},
+		{`fun log(m) { print m; } fun f() { defer log("cleanup"); throw "x"; } try { f(); } catch (e) { print e.message; }`, "cleanup\nx\n", ""},
+		{`fun fail() { throw "deferred"; } fun log(m) { print m; } fun f() { defer log(1); defer fail(); defer log(2); } try { f(); } catch (e) { print e.message; }`, "2\n1\ndeferred\n", ""},
+		{`fun fail(m) { throw m; } fun f() { defer fail("second"); throw "first"; } try { f(); } catch (e) { print e.message; }`, "first\n", ""},
+		{`fun log(m) { print m; } fun inner() { defer log("inner"); } fun outer() { defer log("outer"); inner(); print "body"; } outer();`, "inner\nbody\nouter\n", ""},
+		{`fun log(m) { print m; } fun f() { defer log(1); while (true) {} } f();`, "", exception.LIMIT_EXCEPTION},
+		{`fun f() { defer nope(); } f();`, "", exception.RUNTIME_EXCEPTION},
+		{`fun f() { defer clock(1); } f();`, "", exception.RUNTIME_EXCEPTION},
+	}
+
+	for _, test := range tests {
+		stdout := &bytes.Buffer{}
+		tokens, _ := lexer.New(test.code).Tokenize()
+		stmts, err := parser.New(tokens).Parse()
+		if err != nil {
+			t.Fatalf("failed to parse code %q. got=%v", test.code, err.Error())
+		}
+
+		glox := New(io.Discard, stdout)
+		glox.Quiet = true
+		glox.Limits = Limits{Statements: 1000}
+		_, err = glox.Eval(context.Background(), stmts)
+		var e *exception.Error
+		if test.wantErr == "" && err != nil {
+			t.Fatalf("%q -> unexpected error. got=%v", test.code, err)
+		} else if test.wantErr != "" && (!errors.As(err, &e) || e.Kind != test.wantErr) {
+			t.Fatalf("%q -> wrong error. want kind=%s got=%v", test.code, test.wantErr, err)
+		}
+		if stdout.String() != test.wantOut {
+			t.Fatalf("%q -> wrong output. want=%q got=%q", test.code, test.wantOut, stdout.String())
+		}
+		if glox.Env != glox.globals || glox.deferred != nil {
+			t.Fatalf("%q -> the global environment must be restored", test.code)
+		}
+	}
+}
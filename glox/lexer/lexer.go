@@ -5,14 +5,18 @@ import (
 	"glox/exception"
 	"glox/token"
 	"strconv"
+	"unicode/utf8"
 )
 
 type Lexer struct {
-	Source  string
-	tokens  []token.Token
-	start   int
-	current int
-	line    int
+	Source    string
+	tokens    []token.Token
+	trivia    []token.Trivia // Comments waiting to be attached to the next token.
+	start     int
+	current   int
+	line      int
+	lineStart int // Offset of the first character of the current line.
+	column    int // Column of the token being scanned.
 }
 
 func New(Source string) *Lexer {
@@ -29,10 +33,12 @@ func (lxr *Lexer) Tokenize() ([]token.Token, error) {
 	var err error
 	for !lxr.isAtEnd() {
 		lxr.start = lxr.current
+		lxr.column = lxr.columnAt(lxr.start)
 		err = lxr.lex()
 	}
 
-	lxr.tokens = append(lxr.tokens, token.Token{Type: token.EOF, Literal: nil, Lexeme: "", Line: lxr.line})
+	eof := token.Token{Type: token.EOF, Literal: nil, Lexeme: "", Line: lxr.line, Column: lxr.columnAt(lxr.current), Leading: lxr.trivia}
+	lxr.tokens = append(lxr.tokens, eof)
 	return lxr.tokens, err
 }
 
@@ -101,7 +107,7 @@ func (s *Lexer) lex() error {
 	case '\t':
 		break
 	case '\n':
-		s.line++
+		s.newline()
 	case '"':
 		err = s.string()
 	default:
@@ -114,7 +120,6 @@ func (s *Lexer) lex() error {
 		}
 	}
 
-
 	return err
 }
 
@@ -134,10 +139,32 @@ func (s *Lexer) addTokenType(tokenType token.TokenType) {
 
 func (s *Lexer) addToken(tokenType token.TokenType, literal any) {
 	lexeme := s.Source[s.start:s.current]
-	tok := token.Token{Type: tokenType, Literal: literal, Lexeme: lexeme, Line: s.line}
+	tok := token.Token{Type: tokenType, Literal: literal, Lexeme: lexeme, Line: s.line, Column: s.column, Leading: s.trivia}
+	s.trivia = nil
 	s.tokens = append(s.tokens, tok)
 }
 
+// Records the comment that was just scanned. It trails the previous token when it starts on the same
+// line, otherwise it leads the next token.
+func (s *Lexer) addTrivia(tokenType token.TokenType, line int) {
+	trivia := token.Trivia{Type: tokenType, Lexeme: s.Source[s.start:s.current], Line: line}
+	if last := len(s.tokens) - 1; last >= 0 && len(s.trivia) == 0 && s.tokens[last].Line == line {
+		s.tokens[last].Trailing = append(s.tokens[last].Trailing, trivia)
+		return
+	}
+	s.trivia = append(s.trivia, trivia)
+}
+
+// Must be called once a line break has been consumed.
+func (s *Lexer) newline() {
+	s.line++
+	s.lineStart = s.current
+}
+
+func (s *Lexer) columnAt(offset int) int {
+	return utf8.RuneCountInString(s.Source[s.lineStart:offset]) + 1
+}
+
 func (s *Lexer) match(expect byte) bool {
 	if s.isAtEnd() || s.Source[s.current] != expect {
 		return false
@@ -172,15 +199,14 @@ func (s *Lexer) peek() byte {
 // tokenizes a string literal.
 func (s *Lexer) string() error {
 	for s.peek() != '"' && !s.isAtEnd() {
-		if s.peek() == '\n' {
+		if s.advance() == '\n' {
 			// Multi-line string literals are allowed
-			s.line++
+			s.newline()
 		}
-		s.advance()
 	}
 
 	if s.isAtEnd() {
-		return exception.Short(s.line, "Please add a double-quote at the end of the string.")
+		return exception.AtEnd(exception.Short(s.line, s.column, "Please add a double-quote at the end of the string."))
 
 	}
 
@@ -206,7 +232,7 @@ func (s *Lexer) number() error {
 	literal := s.Source[s.start:s.current]
 	value, err := strconv.ParseFloat(literal, 64)
 	if err != nil {
-		return exception.Short(s.line, fmt.Sprintf("%q is an invalid %q", literal, token.NUMBER))
+		return exception.Short(s.line, s.column, fmt.Sprintf("%q is an invalid %q", literal, token.NUMBER))
 
 	}
 	s.addToken(token.NUMBER, value)
@@ -244,29 +270,35 @@ func (s *Lexer) identifier() {
 }
 
 func (s *Lexer) slash() error {
+	line := s.line
 	if s.match('/') {
 		for s.peek() != '\n' && !s.isAtEnd() {
 			s.advance()
 		}
-		literal := s.Source[s.start+2 : s.current]
-		s.addToken(token.SLASH_SLASH, literal)
+		s.addTrivia(token.SLASH_SLASH, line)
 
 	} else if s.match('*') {
-		for s.peek() != '*' && !s.isAtEnd() {
-			s.advance()
-        }
+		// Block comments can be nested, each `/*` must be closed by its own `*/`.
+		depth := 1
+		for depth > 0 && !s.isAtEnd() {
+			if s.peek() == '/' && s.peekNext() == '*' {
+				s.current += 2
+				depth++
+			} else if s.peek() == '*' && s.peekNext() == '/' {
+				s.current += 2
+				depth--
+			} else if s.advance() == '\n' {
+				s.newline()
+			}
+		}
 
-		if s.match('/') {
-			literal := s.Source[s.start+2 : s.current-2]
-			s.addToken(token.SLASK_ASTERISK, literal)
-		} else {
-			return exception.Short(s.line, "opened multi-line comment has not been closed.")
+		if depth > 0 {
+			return exception.AtEnd(exception.Short(line, s.column, "opened multi-line comment has not been closed."))
 		}
+		s.addTrivia(token.SLASK_ASTERISK, line)
 
 	} else {
 		s.addTokenType(token.SLASH)
 	}
 	return nil
 }
-
-
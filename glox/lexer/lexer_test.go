@@ -74,7 +74,6 @@ let age = 12;
 		{token.TRUE, "true"},
 		{token.BANG_EQ, "!="},
 		{token.FALSE, "false"},
-		{token.SLASH_SLASH, "// this is a comment"},
 		{token.THIS, "this"},
 		{token.DOT, "."},
 		{token.IDENTIFIER, "name"},
@@ -125,6 +124,15 @@ let age = 12;
 		}
 	}
 
+	for _, tok := range tokens {
+		if tok.Type == token.THIS {
+			if len(tok.Leading) != 1 || tok.Leading[0].Lexeme != "// this is a comment" {
+				t.Fatalf("comment must lead the next token instead of being in the token stream. got=%v", tok.Leading)
+			}
+			break
+		}
+	}
+
 	input = `"`
 	l = New(input)
 	if _, got := l.Tokenize(); got == nil {
@@ -136,3 +144,107 @@ let age = 12;
 	}
 
 }
+
+func TestTrivia(t *testing.T) {
+	input := `// leading
+let a = 1; // trailing
+/* block with * and ** inside */ print a;
+/* outer /* nested */ still
+   a comment */
+print /* inline */ a;
+// end of file`
+
+	tokens, err := New(input).Tokenize()
+	if err != nil {
+		t.Fatalf("failed to scan code. got error='%s'", err.Error())
+	}
+
+	tests := []struct {
+		tokenIndex int
+		leading    []token.Trivia
+		trailing   []token.Trivia
+	}{
+		{tokenIndex: 0, leading: []token.Trivia{{Type: token.SLASH_SLASH, Lexeme: "// leading", Line: 1}}},
+		{tokenIndex: 4, trailing: []token.Trivia{{Type: token.SLASH_SLASH, Lexeme: "// trailing", Line: 2}}},
+		{tokenIndex: 5, leading: []token.Trivia{{Type: token.SLASK_ASTERISK, Lexeme: "/* block with * and ** inside */", Line: 3}}},
+		{tokenIndex: 8, leading: []token.Trivia{{Type: token.SLASK_ASTERISK, Lexeme: "/* outer /* nested */ still\n   a comment */", Line: 4}}},
+		{tokenIndex: 8, trailing: []token.Trivia{{Type: token.SLASK_ASTERISK, Lexeme: "/* inline */", Line: 6}}},
+		{tokenIndex: 11, leading: []token.Trivia{{Type: token.SLASH_SLASH, Lexeme: "// end of file", Line: 7}}},
+	}
+
+	types := []token.TokenType{token.LET, token.IDENTIFIER, token.EQUAL, token.NUMBER, token.SEMICOLON, token.PRINT, token.IDENTIFIER, token.SEMICOLON, token.PRINT, token.IDENTIFIER, token.SEMICOLON, token.EOF}
+	if len(tokens) != len(types) {
+		t.Fatalf("comments must not be in the token stream. want %d tokens got %d", len(types), len(tokens))
+	}
+	for i, tok := range tokens {
+		if tok.Type != types[i] {
+			t.Fatalf("wrong token type at %d. expected %q got %q", i, types[i], tok.Type)
+		}
+	}
+
+	for _, test := range tests {
+		tok := tokens[test.tokenIndex]
+		if test.leading != nil && !equalTrivia(tok.Leading, test.leading) {
+			t.Fatalf("wrong leading trivia for token %d (%s). want=%v got=%v", test.tokenIndex, tok.Lexeme, test.leading, tok.Leading)
+		}
+		if test.trailing != nil && !equalTrivia(tok.Trailing, test.trailing) {
+			t.Fatalf("wrong trailing trivia for token %d (%s). want=%v got=%v", test.tokenIndex, tok.Lexeme, test.trailing, tok.Trailing)
+		}
+	}
+
+	if tokens[8].Line != 6 {
+		t.Fatalf("multi-line comments must advance the line count. want=6 got=%d", tokens[8].Line)
+	}
+	if got := tokens[8].Leading[0].Text(); got != " outer /* nested */ still\n   a comment " {
+		t.Fatalf("wrong text for block comment. got=%q", got)
+	}
+
+	input = `/* outer /* nested */`
+	if _, err := New(input).Tokenize(); err == nil || !strings.Contains(err.Error(), "multi-line comment has not been closed") {
+		t.Fatalf("failed to capture unclosed comment in code '%s'. got=%v", input, err)
+	}
+}
+
+func TestColumns(t *testing.T) {
+	input := "let s = \"é\nb\";\n\tprint \"é\" /* c */ + s;"
+	tokens, err := New(input).Tokenize()
+	if err != nil {
+		t.Fatalf("Scanning failed with exception='%v'", err.Error())
+	}
+
+	tests := []struct {
+		lexeme string
+		line   int
+		column int
+	}{
+		{"let", 1, 1},
+		{"s", 1, 5},
+		{"=", 1, 7},
+		{"\"é\nb\"", 2, 9},
+		{";", 2, 3},
+		{"print", 3, 2},
+		{"\"é\"", 3, 8},
+		{"+", 3, 20},
+		{"s", 3, 22},
+		{";", 3, 23},
+		{"", 3, 24},
+	}
+	for i, test := range tests {
+		tok := tokens[i]
+		if tok.Lexeme != test.lexeme || tok.Line != test.line || tok.Column != test.column {
+			t.Fatalf("tests[%d] - wrong position. want=%q %d:%d got=%q %d:%d", i, test.lexeme, test.line, test.column, tok.Lexeme, tok.Line, tok.Column)
+		}
+	}
+}
+
+func equalTrivia(got []token.Trivia, want []token.Trivia) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
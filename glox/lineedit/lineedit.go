@@ -0,0 +1,408 @@
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Number of lines kept in the history, older ones are dropped.
+const HISTORY_SIZE = 1000
+
+// Returned by `ReadLine` when the user presses Ctrl-C.
+var ErrInterrupted = errors.New("interrupted")
+
+// Keys read from the terminal, the ones sent as escape sequences are given negative values so that they
+// cannot be mistaken for a character.
+const (
+	ctrlA     = 1
+	ctrlB     = 2
+	ctrlC     = 3
+	ctrlD     = 4
+	ctrlE     = 5
+	ctrlF     = 6
+	ctrlG     = 7
+	ctrlH     = 8
+	tab       = 9
+	ctrlK     = 11
+	ctrlL     = 12
+	ctrlN     = 14
+	ctrlP     = 16
+	ctrlR     = 18
+	ctrlU     = 21
+	ctrlW     = 23
+	newline   = '\n'
+	enter     = '\r'
+	esc       = 27
+	backspace = 127
+
+	keyUp     = -1
+	keyDown   = -2
+	keyRight  = -3
+	keyLeft   = -4
+	keyHome   = -5
+	keyEnd    = -6
+	keyDelete = -7
+	keyNone   = -8 // An escape sequence that is not supported.
+)
+
+// Editor reads lines typed in a terminal, letting the user move the cursor, edit the line and recall the
+// previous ones. The terminal is only put in raw mode while a line is being read.
+type Editor struct {
+	History []string // Oldest line first.
+	// Complete returns the words starting with the given prefix, it is called when Tab is pressed after a
+	// word. Nothing is completed when it is nil.
+	Complete func(prefix string) []string
+	in       *bufio.Reader
+	out      io.Writer
+	tty      *os.File // nil when the input is not a terminal, e.g. in tests.
+}
+
+func New(in io.Reader, out io.Writer) *Editor {
+	editor := &Editor{in: bufio.NewReader(in), out: out}
+	if f, isFile := in.(*os.File); isFile && IsTerminal(f) {
+		editor.tty = f
+	}
+	return editor
+}
+
+// ReadLine shows the prompt and returns the line typed by the user, without its line break. It returns
+// io.EOF when Ctrl-D is pressed on an empty line and ErrInterrupted when Ctrl-C is pressed.
+func (e *Editor) ReadLine(prompt string) (string, error) {
+	if e.tty != nil {
+		restore, err := makeRaw(e.tty)
+		if err != nil {
+			return "", err
+		}
+		defer restore()
+	}
+
+	s := &session{editor: e, prompt: prompt, history: len(e.History)}
+	s.refresh()
+	for {
+		key, err := e.readKey()
+		if err != nil {
+			if err == io.EOF && len(s.line) > 0 {
+				err = nil
+			}
+			fmt.Fprint(e.out, "\r\n")
+			return string(s.line), err
+		}
+		for key == ctrlR {
+			if key, err = s.search(); err != nil {
+				return "", err
+			}
+		}
+
+		if line, done, err := s.handle(key); done {
+			fmt.Fprint(e.out, "\r\n")
+			return line, err
+		}
+	}
+}
+
+// AddHistory records a line typed by the user, blank lines and repetitions of the last line are ignored.
+func (e *Editor) AddHistory(line string) {
+	if strings.TrimSpace(line) == "" || (len(e.History) > 0 && e.History[len(e.History)-1] == line) {
+		return
+	}
+	e.History = append(e.History, line)
+	if len(e.History) > HISTORY_SIZE {
+		e.History = e.History[len(e.History)-HISTORY_SIZE:]
+	}
+}
+
+// LoadHistory reads the history saved in a file with one line per entry. A missing file is not an error.
+func (e *Editor) LoadHistory(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		e.AddHistory(line)
+	}
+	return nil
+}
+
+// SaveHistory writes the history to a file with one line per entry.
+func (e *Editor) SaveHistory(path string) error {
+	data := strings.Join(e.History, "\n")
+	if len(e.History) > 0 {
+		data += "\n"
+	}
+	return os.WriteFile(path, []byte(data), 0600)
+}
+
+func (e *Editor) readKey() (rune, error) {
+	key, _, err := e.in.ReadRune()
+	if err != nil || key != esc {
+		return key, err
+	}
+
+	// Arrows and the other special keys are sent as `ESC [ X`, `ESC O X` or `ESC [ n ~`.
+	prefix, _, err := e.in.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	if prefix != '[' && prefix != 'O' {
+		return keyNone, nil
+	}
+	code, _, err := e.in.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	switch code {
+	case 'A':
+		return keyUp, nil
+	case 'B':
+		return keyDown, nil
+	case 'C':
+		return keyRight, nil
+	case 'D':
+		return keyLeft, nil
+	case 'H':
+		return keyHome, nil
+	case 'F':
+		return keyEnd, nil
+	}
+
+	number := ""
+	for '0' <= code && code <= '9' || code == ';' {
+		number += string(code)
+		if code, _, err = e.in.ReadRune(); err != nil {
+			return 0, err
+		}
+	}
+	if code != '~' {
+		return keyNone, nil
+	}
+	switch number {
+	case "1", "7":
+		return keyHome, nil
+	case "4", "8":
+		return keyEnd, nil
+	case "3":
+		return keyDelete, nil
+	}
+	return keyNone, nil
+}
+
+// The line being read.
+type session struct {
+	editor  *Editor
+	prompt  string
+	line    []rune
+	pos     int    // Position of the cursor in the line.
+	history int    // Index of the history entry shown, len(History) for the line being typed.
+	typed   []rune // The line being typed, kept while browsing the history.
+}
+
+// Handles a key press, it returns true when the line is complete.
+func (s *session) handle(key rune) (string, bool, error) {
+	switch key {
+	case enter, newline:
+		return string(s.line), true, nil
+	case ctrlC:
+		return "", true, ErrInterrupted
+	case ctrlD:
+		if len(s.line) == 0 {
+			return "", true, io.EOF
+		}
+		s.delete(s.pos, s.pos+1)
+	case ctrlA, keyHome:
+		s.pos = 0
+	case ctrlE, keyEnd:
+		s.pos = len(s.line)
+	case ctrlB, keyLeft:
+		if s.pos > 0 {
+			s.pos--
+		}
+	case ctrlF, keyRight:
+		if s.pos < len(s.line) {
+			s.pos++
+		}
+	case backspace, ctrlH:
+		if s.pos > 0 {
+			s.delete(s.pos-1, s.pos)
+		}
+	case keyDelete:
+		s.delete(s.pos, s.pos+1)
+	case ctrlW:
+		start := s.pos
+		for start > 0 && unicode.IsSpace(s.line[start-1]) {
+			start--
+		}
+		for start > 0 && !unicode.IsSpace(s.line[start-1]) {
+			start--
+		}
+		s.delete(start, s.pos)
+	case ctrlU:
+		s.delete(0, s.pos)
+	case ctrlK:
+		s.delete(s.pos, len(s.line))
+	case ctrlP, keyUp:
+		s.browse(s.history - 1)
+	case ctrlN, keyDown:
+		s.browse(s.history + 1)
+	case ctrlL:
+		fmt.Fprint(s.editor.out, "\x1b[H\x1b[2J")
+	case tab:
+		s.complete()
+	default:
+		if !unicode.IsPrint(key) {
+			return "", false, nil
+		}
+		s.insert([]rune{key})
+	}
+	s.refresh()
+	return "", false, nil
+}
+
+func (s *session) insert(text []rune) {
+	s.line = append(s.line[:s.pos], append(text, s.line[s.pos:]...)...)
+	s.pos += len(text)
+}
+
+func (s *session) delete(from int, to int) {
+	if to > len(s.line) {
+		to = len(s.line)
+	}
+	if from >= to {
+		return
+	}
+	s.line = append(s.line[:from], s.line[to:]...)
+	s.pos = from
+}
+
+// Completes the word before the cursor with the longest prefix shared by the candidates. When that does
+// not extend the word, the candidates are listed below the line.
+func (s *session) complete() {
+	start := s.pos
+	for start > 0 && isWordRune(s.line[start-1]) {
+		start--
+	}
+	if s.editor.Complete == nil || start == s.pos {
+		return
+	}
+
+	word := s.line[start:s.pos]
+	candidates := s.editor.Complete(string(word))
+	if len(candidates) == 0 {
+		return
+	}
+	if common := commonPrefix(candidates); len(common) > len(word) {
+		s.insert(common[len(word):])
+	} else if len(candidates) > 1 {
+		fmt.Fprintf(s.editor.out, "\r\n%s\r\n", strings.Join(candidates, "  "))
+	}
+}
+
+func commonPrefix(words []string) []rune {
+	prefix := []rune(words[0])
+	for _, word := range words[1:] {
+		runes := []rune(word)
+		i := 0
+		for i < len(prefix) && i < len(runes) && prefix[i] == runes[i] {
+			i++
+		}
+		prefix = prefix[:i]
+	}
+	return prefix
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Shows the history entry at index, the line being typed is kept so that it can be browsed back to.
+func (s *session) browse(index int) {
+	history := s.editor.History
+	if index < 0 || index > len(history) {
+		return
+	}
+	if s.history == len(history) {
+		s.typed = s.line
+	}
+	s.history = index
+	if index == len(history) {
+		s.line = s.typed
+	} else {
+		s.line = []rune(history[index])
+	}
+	s.pos = len(s.line)
+}
+
+// Reverse incremental search started with Ctrl-R: every character typed narrows the search, Ctrl-R looks
+// for an older match and Ctrl-G cancels. Any other key accepts the match and is returned to be handled.
+func (s *session) search() (rune, error) {
+	history := s.editor.History
+	query := []rune{}
+	match := len(history)
+
+	find := func(from int) {
+		for i := from; i >= 0; i-- {
+			if strings.Contains(history[i], string(query)) {
+				match = i
+				return
+			}
+		}
+	}
+
+	for {
+		found := ""
+		if match < len(history) {
+			found = history[match]
+		}
+		fmt.Fprintf(s.editor.out, "\r(reverse-i-search)`%s': %s\x1b[K", string(query), found)
+
+		key, err := s.editor.readKey()
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case key == ctrlR:
+			find(match - 1)
+		case key == backspace || key == ctrlH:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+			}
+			match = len(history)
+			find(len(history) - 1)
+		case key == ctrlG || key == ctrlC:
+			s.refresh()
+			return keyNone, nil
+		case key > 0 && unicode.IsPrint(key):
+			query = append(query, key)
+			// The current match is kept if it still matches.
+			if match == len(history) {
+				match--
+			}
+			find(match)
+		default:
+			if match < len(history) {
+				s.history = match
+				s.line = []rune(history[match])
+				s.pos = len(s.line)
+			}
+			s.refresh()
+			return key, nil
+		}
+	}
+}
+
+// Redraws the line: back to the first column, the prompt and the line, the end of the previous line is
+// cleared and the cursor is moved to its position.
+func (s *session) refresh() {
+	out := &strings.Builder{}
+	out.WriteString("\r" + s.prompt + string(s.line) + "\x1b[K\r")
+	if column := len([]rune(s.prompt)) + s.pos; column > 0 {
+		fmt.Fprintf(out, "\x1b[%dC", column)
+	}
+	io.WriteString(s.editor.out, out.String())
+}
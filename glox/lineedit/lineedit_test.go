@@ -0,0 +1,165 @@
+package lineedit
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadLine(t *testing.T) {
+	history := []string{`print "hello";`, `let age = 12;`, `print age;`}
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"plain line", "print 1;\r", "print 1;"},
+		{"line feed", "print 1;\n", "print 1;"},
+		{"backspace", "print 12\x7f;\r", "print 1;"},
+		{"left arrow and insert", "print ;\x1b[D1\r", "print 1;"},
+		{"home and end", "rint 1\x1b[Hp\x1b[F;\r", "print 1;"},
+		{"ctrl-a and ctrl-e", "rint 1\x01p\x05;\r", "print 1;"},
+		{"ctrl-b and ctrl-f", "pint\x02\x02\x02r\x06\x06\x06 1;\r", "print 1;"},
+		{"delete key", "print 11;\x1b[D\x1b[D\x1b[3~\r", "print 1;"},
+		{"ctrl-w", "print hello world\x17\x17age;\r", "print age;"},
+		{"ctrl-u", "let x = 1;\x1b[D\x15\r", ";"},
+		{"ctrl-k", "print 1; junk\x1b[D\x1b[D\x1b[D\x1b[D\x1b[D\x0b\r", "print 1;"},
+		{"ctrl-d deletes", "print 11;\x01\x04\r", "rint 11;"},
+		{"up arrow", "\x1b[A\r", "print age;"},
+		{"up twice", "\x1b[A\x1b[A\r", "let age = 12;"},
+		{"up past the oldest", "\x1b[A\x1b[A\x1b[A\x1b[A\r", `print "hello";`},
+		{"up and down keeps typed", "pri\x1b[A\x1b[B\r", "pri"},
+		{"ctrl-p and ctrl-n", "\x10\x10\x0e\r", "print age;"},
+		{"reverse search", "\x12age\r", "print age;"},
+		{"reverse search again", "\x12age\x12\r", "let age = 12;"},
+		{"reverse search then edit", "\x12hello\x05 // hi\r", `print "hello"; // hi`},
+		{"reverse search cancelled", "x\x12age\x07\r", "x"},
+		{"unicode", "print \"é\";\x1b[D\x1b[D\x7fè\r", `print "è";`},
+		{"unsupported keys are ignored", "print\x1b[15~\x1bx 1;\r", "print 1;"},
+	}
+
+	for _, test := range tests {
+		out := &bytes.Buffer{}
+		editor := New(strings.NewReader(test.input), out)
+		editor.History = append([]string{}, history...)
+
+		got, err := editor.ReadLine(">> ")
+		if err != nil {
+			t.Fatalf("%s: unexpected error. got=%v", test.name, err)
+		}
+		if got != test.want {
+			t.Fatalf("%s: wrong line. want=%q got=%q", test.name, test.want, got)
+		}
+	}
+}
+
+func TestReadLineEnd(t *testing.T) {
+	editor := New(strings.NewReader("\x04"), io.Discard)
+	if _, err := editor.ReadLine(">> "); err != io.EOF {
+		t.Fatalf("ctrl-d on an empty line must end the input. got=%v", err)
+	}
+
+	editor = New(strings.NewReader("print 1;\x03"), io.Discard)
+	if _, err := editor.ReadLine(">> "); err != ErrInterrupted {
+		t.Fatalf("ctrl-c must interrupt the line. got=%v", err)
+	}
+
+	editor = New(strings.NewReader("print 1;"), io.Discard)
+	if got, err := editor.ReadLine(">> "); err != nil || got != "print 1;" {
+		t.Fatalf("the last line must be returned even without a line break. got=%q err=%v", got, err)
+	}
+	if _, err := editor.ReadLine(">> "); err != io.EOF {
+		t.Fatalf("expected the end of the input. got=%v", err)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	words := []string{"class", "clock", "print", "printer", "while"}
+	complete := func(prefix string) []string {
+		matches := []string{}
+		for _, word := range words {
+			if strings.HasPrefix(word, prefix) {
+				matches = append(matches, word)
+			}
+		}
+		return matches
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantOut string
+	}{
+		{"single match", "wh\t (true);\r", "while (true);", ""},
+		{"common prefix", "pr\t;\r", "print;", ""},
+		{"ambiguous lists", "cl\t\r", "cl", "\r\nclass  clock\r\n"},
+		{"word before the cursor", "x = clo;\x1b[D\t\r", "x = clock;", ""},
+		{"no word", "\t1\r", "1", ""},
+		{"no match", "zz\t\r", "zz", ""},
+	}
+
+	for _, test := range tests {
+		out := &bytes.Buffer{}
+		editor := New(strings.NewReader(test.input), out)
+		editor.Complete = complete
+
+		got, err := editor.ReadLine(">> ")
+		if err != nil {
+			t.Fatalf("%s: unexpected error. got=%v", test.name, err)
+		}
+		if got != test.want {
+			t.Fatalf("%s: wrong line. want=%q got=%q", test.name, test.want, got)
+		}
+		if !strings.Contains(out.String(), test.wantOut) {
+			t.Fatalf("%s: wrong output. want to contain=%q got=%q", test.name, test.wantOut, out.String())
+		}
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	out := &bytes.Buffer{}
+	editor := New(strings.NewReader("ab\x1b[D\r"), out)
+	editor.ReadLine(">> ")
+
+	// The line is redrawn with the cursor moved back before `b`.
+	if want := "\r>> ab\x1b[K\r\x1b[4C"; !strings.Contains(out.String(), want) {
+		t.Fatalf("wrong output. want to contain=%q got=%q", want, out.String())
+	}
+}
+
+func TestHistory(t *testing.T) {
+	editor := New(strings.NewReader(""), io.Discard)
+	for _, line := range []string{"print 1;", "print 1;", "  ", "print 2;"} {
+		editor.AddHistory(line)
+	}
+	if got := strings.Join(editor.History, "|"); got != "print 1;|print 2;" {
+		t.Fatalf("wrong history. got=%q", got)
+	}
+
+	path := filepath.Join(t.TempDir(), ".glox_history")
+	if err := editor.LoadHistory(path); err != nil {
+		t.Fatalf("a missing history file must be ignored. got=%v", err)
+	}
+	if err := editor.SaveHistory(path); err != nil {
+		t.Fatalf("failed to save history. got=%v", err)
+	}
+
+	loaded := New(strings.NewReader(""), io.Discard)
+	if err := loaded.LoadHistory(path); err != nil {
+		t.Fatalf("failed to load history. got=%v", err)
+	}
+	if got := strings.Join(loaded.History, "|"); got != "print 1;|print 2;" {
+		t.Fatalf("wrong history loaded. got=%q", got)
+	}
+
+	for i := 0; i < HISTORY_SIZE+10; i++ {
+		loaded.AddHistory(strings.Repeat("x", i+1))
+	}
+	if len(loaded.History) != HISTORY_SIZE {
+		t.Fatalf("history must be capped. want=%d got=%d", HISTORY_SIZE, len(loaded.History))
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package lineedit
+
+import (
+	"errors"
+	"os"
+)
+
+// Raw mode is not supported on this platform, the REPL falls back to reading whole lines.
+func IsTerminal(f *os.File) bool {
+	return false
+}
+
+func makeRaw(f *os.File) (func() error, error) {
+	return nil, errors.New("raw terminal mode is not supported on this platform")
+}
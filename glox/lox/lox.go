@@ -1,82 +1,113 @@
 package lox
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
+	"glox/ast"
+	"glox/capability"
+	"glox/exception"
 	"glox/interpreter"
 	"glox/lexer"
+	"glox/native"
+	"glox/optimize"
 	"glox/parser"
+	"glox/source"
+	"glox/value"
 	"io"
-	"os"
 )
 
-const PROMPT = ">> "
+// Programs that cannot run until their end fail with one of these errors, wrapping the original one. By the
+// time they are returned they are already reported to the stderr, other errors (e.g. a missing file) are not.
+var (
+	ErrSyntax  = errors.New("syntax error")
+	ErrRuntime = errors.New("runtime error")
+)
 
 type Runner interface {
 	RunFile(path string) error
+	Run(src *source.Source) error
 	StartREPL(stdin io.Reader)
 }
 
-func NewRunner(stdErr io.Writer, stdout io.Writer) Runner {
-	return &Lox{stdErr: stdErr, stdout: stdout}
+type Options struct {
+	Optimize bool     // Runs the `optimize` pass on every parsed program before interpreting it.
+	Args     []string // Arguments passed to the script, available through the `argc()` and `args(i)` natives.
+
+	// What scripts may do outside the interpreter, e.g. reading files with `readFile`. Nothing by default.
+	Capabilities capability.Set
+
+	// Prompts shown by the REPL, PROMPT and CONTINUATION_PROMPT when empty.
+	Prompt             string
+	ContinuationPrompt string
+}
+
+func NewRunner(stdErr io.Writer, stdout io.Writer, opts Options) Runner {
+	return &Lox{stdErr: stdErr, stdout: stdout, opts: opts}
 }
 
 type Lox struct {
 	stdErr io.Writer
 	stdout io.Writer
+	opts   Options
 }
 
 func (r *Lox) RunFile(path string) error {
-	file, err := os.Open(path)
+	src, err := source.Load(path)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	return r.Run(src)
+}
+
+func (r *Lox) Run(src *source.Source) error {
+	glox := r.interpreter()
+	glox.Source = src.Name
+	return r.run(src, glox)
+}
 
-	info, err := file.Stat()
+func (r *Lox) run(src *source.Source, glox *interpreter.Interpreter) error {
+	stmts, err := compile(src, r.opts.Optimize)
 	if err != nil {
-		return err
+		fmt.Fprintf(r.stdErr, "%v\n", err.Error())
+		return fmt.Errorf("%w: %w", ErrSyntax, err)
 	}
 
-	glox := interpreter.New(r.stdErr, r.stdout)
-
-	bytes := make([]byte, info.Size())
-	if _, err = bufio.NewReader(file).Read(bytes); err != nil {
-		return err
+	if err := glox.Interpret(stmts); err != nil {
+		return fmt.Errorf("%w: %w", ErrRuntime, err)
 	}
-	r.run(string(bytes), glox)
 	return nil
 }
 
-func (r *Lox) StartREPL(stdin io.Reader) {
-	scanner := bufio.NewScanner(stdin)
-	glox := interpreter.New(r.stdErr, r.stdout)
-
-	for {
-		fmt.Print(PROMPT)
-		if !scanner.Scan() {
-			return
-		}
-
-		line := scanner.Text()
-		r.run(line, glox)
-	}
-}
-
-func (r *Lox) run(src string, glox *interpreter.Interpreter) {
-	scnr := lexer.New(src)
-
-	tokens, err := scnr.Tokenize()
+// Lexes and parses a program, errors are located in the source.
+func compile(src *source.Source, optimized bool) ([]ast.Statement, error) {
+	tokens, err := lexer.New(src.Code).Tokenize()
 	if err != nil {
-		fmt.Fprintf(r.stdErr, "%v\n", err.Error())
+		return nil, exception.Locate(err, src.Name)
 	}
-	prsr := parser.New(tokens)
-	exp, err := prsr.Parse()
-
+	stmts, err := parser.New(tokens).Parse()
 	if err != nil {
-		fmt.Fprintf(r.stdErr, "%v\n", err.Error())
-		return
+		return nil, exception.Locate(err, src.Name)
 	}
+	if optimized {
+		stmts = optimize.Program(stmts)
+	}
+	return stmts, nil
+}
 
-	glox.Interpret(exp)
+func (r *Lox) interpreter() *interpreter.Interpreter {
+	glox := r.newInterpreter()
+	// Modules are read like any other file, they must be allowed with `--allow-read`.
+	glox.Importer = newModules(r.opts.Optimize, r.newInterpreter, func(path string) error {
+		return r.opts.Capabilities.Check(capability.READ, path)
+	})
+	return glox
+}
+
+// Returns an interpreter with the natives of the runner, the modules imported by a script use one too.
+func (r *Lox) newInterpreter() *interpreter.Interpreter {
+	glox := interpreter.New(r.stdErr, r.stdout)
+	glox.Capabilities = r.opts.Capabilities
+	glox.Env.Define("argc", value.Object(native.Argc[*interpreter.Interpreter](r.opts.Args)))
+	glox.Env.Define("args", value.Object(native.Args[*interpreter.Interpreter](r.opts.Args)))
+	return glox
 }
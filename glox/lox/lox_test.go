@@ -0,0 +1,483 @@
+package lox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"glox/capability"
+	"glox/exception"
+	"glox/source"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIncomplete(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{`print 1;`, false},
+		{`fun greet(name) {`, true},
+		{"fun greet(name) {\n  print name;", true},
+		{"fun greet(name) {\n  print name;\n}", false},
+		{`while (i < 10`, true},
+		{`print (1 +`, true},
+		{`let s = "multi`, true},
+		{`/* still a comment`, true},
+		{`print 1`, true},
+		{`print 1;}`, false},
+		{`print );`, false},
+		{`let = 1;`, false},
+	}
+
+	for _, test := range tests {
+		if got := incomplete(test.code); got != test.want {
+			t.Fatalf("incomplete(%q) has wrong value. want=%t got=%t", test.code, test.want, got)
+		}
+	}
+}
+
+func TestMetaCommands(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "lib.lox")
+	if err := os.WriteFile(script, []byte("let loaded = \"yes\";\nprint nope;"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input   string
+		wantOut []string
+		wantErr []string
+	}{
+		{":help", []string{":load <file>", ":time <code>"}, nil},
+		{"let age = 12;\nlet name = \"anya\";\n:env", []string{"clock = <native fn>", "age = 12", "name = \"anya\""}, nil},
+		{":load " + script + "\nprint loaded;", []string{"yes"}, []string{script + ":2:7:"}},
+		{":load missing.lox", nil, []string{"missing.lox"}},
+		{"let age = 12;\n:reset\nprint age;", nil, []string{"undefined variable 'age'"}},
+		{":ast print 1;", []string{`"kind": "print"`}, nil},
+		{":tokens print 1;", []string{"PRINT", "SEMICOLON"}, nil},
+		{":time print 1 + 1;", []string{"2\ntook "}, nil},
+		{":load", nil, []string{"usage: :load <file>"}},
+		{":nope", nil, []string{"unknown command ':nope'"}},
+	}
+
+	for _, test := range tests {
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		NewRunner(stderr, stdout, Options{}).StartREPL(strings.NewReader(test.input))
+
+		for _, want := range test.wantOut {
+			if !strings.Contains(stdout.String(), want) {
+				t.Fatalf("%q -> stdout does not contain %q. got=%q", test.input, want, stdout.String())
+			}
+		}
+		for _, want := range test.wantErr {
+			if !strings.Contains(stderr.String(), want) {
+				t.Fatalf("%q -> stderr does not contain %q. got=%q", test.input, want, stderr.String())
+			}
+		}
+		if len(test.wantErr) == 0 && stderr.Len() > 0 {
+			t.Fatalf("%q -> unexpected stderr. got=%q", test.input, stderr.String())
+		}
+	}
+}
+
+func TestComplete(t *testing.T) {
+	r := &Lox{stdErr: &bytes.Buffer{}, stdout: &bytes.Buffer{}}
+	s := &session{Lox: r, glox: r.interpreter()}
+	s.run(source.New("", "let printer = 1; let primes = 2;"), s.glox)
+
+	tests := []struct {
+		prefix string
+		want   string
+	}{
+		{"pri", "primes print printer"},
+		{"cl", "class clock"},
+		{"wh", "while"},
+		{"zzz", ""},
+	}
+
+	for _, test := range tests {
+		if got := strings.Join(s.complete(test.prefix), " "); got != test.want {
+			t.Fatalf("%q -> wrong completions. want=%q got=%q", test.prefix, test.want, got)
+		}
+	}
+}
+
+// Runs the REPL on input and returns everything it wrote, stdout and stderr interleaved as they would be
+// in a terminal.
+func transcript(input string, opts Options) string {
+	out := &bytes.Buffer{}
+	NewRunner(out, out, opts).StartREPL(strings.NewReader(input))
+	return out.String()
+}
+
+func TestTranscript(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"print 1;\nprint 2;", "1\n2\n"},
+		{"let x = 1;\n\nx + 1;", "2\n"},
+		{"fun add(a, b) {\n  print a + b;\n}\nadd(1, 2);", "3\n<nil>\n"},
+		{"print nope;\nprint 1;", "unhandled exception: RuntimeException(\"nope\", undefined variable 'nope'.)\n[line: 1]\n1\n"},
+		{"print (1;\n\nprint 2;", "unhandled exception: GenericException(expected ')' after expression at ';')\n[line: 1]\n2\n"},
+		{"print 1 +\n", "unhandled exception: ParseException(\"\", illegal token)\n[line: 1]\n"},
+	}
+
+	for _, test := range tests {
+		if got := transcript(test.input, Options{Prompt: "lox> "}); got != test.want {
+			t.Fatalf("%q -> wrong transcript. want=%q got=%q", test.input, test.want, got)
+		}
+	}
+}
+
+func TestVM(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	vm := NewVM(VMOptions{Stdout: stdout})
+	ctx := context.Background()
+
+	if err := vm.Set("limit", int64(10)); err != nil {
+		t.Fatalf("unexpected error. got=%v", err)
+	}
+	if err := vm.Set("name", "anya"); err != nil {
+		t.Fatalf("unexpected error. got=%v", err)
+	}
+	if err := vm.Set("half", func(n float64) (float64, error) {
+		if n < 0 {
+			return 0, errors.New("negative number")
+		}
+		return n / 2, nil
+	}); err != nil {
+		t.Fatalf("unexpected error. got=%v", err)
+	}
+	type customer struct {
+		Name  string
+		Spent float64
+	}
+	buyer := &customer{Name: "anya"}
+	if err := vm.Set("buyer", buyer); err != nil {
+		t.Fatalf("unexpected error. got=%v", err)
+	}
+	if err := vm.Set("chan", make(chan int)); err == nil {
+		t.Fatalf("expected an error converting a channel")
+	}
+
+	tests := []struct {
+		code    string
+		want    any
+		wantErr error
+	}{
+		{`limit * 2;`, 20.0, nil},
+		{`let greeting = "hello " + name; greeting;`, "hello anya", nil},
+		{`print limit; let x = 1;`, nil, nil},
+		{`greeting == "hello anya";`, true, nil},
+		{`print (1;`, nil, ErrSyntax},
+		{`nope + 1;`, nil, ErrRuntime},
+		{`half(limit);`, 5.0, nil},
+		{`half(-1);`, nil, ErrRuntime},
+		{`half("1");`, nil, ErrRuntime},
+		{`buyer.spent = buyer.spent + limit; buyer.name;`, "anya", nil},
+		{`buyer.nope;`, nil, ErrRuntime},
+	}
+
+	for _, test := range tests {
+		got, err := vm.Eval(ctx, test.code)
+		if !errors.Is(err, test.wantErr) {
+			t.Fatalf("%q -> wrong error. want=%v got=%v", test.code, test.wantErr, err)
+		}
+		if got.Interface() != test.want {
+			t.Fatalf("%q -> wrong value. want=%v got=%v", test.code, test.want, got)
+		}
+	}
+
+	if stdout.String() != "10\n" {
+		t.Fatalf("only print statements must write to stdout. got=%q", stdout.String())
+	}
+	if buyer.Spent != 10 {
+		t.Fatalf("assignments to host objects must reach Go. got=%v", buyer.Spent)
+	}
+	if val, isOk := vm.Get("greeting"); !isOk || val.AsString() != "hello anya" {
+		t.Fatalf("wrong global. got=%v, %t", val, isOk)
+	}
+	if _, isOk := vm.Get("missing"); isOk {
+		t.Fatalf("undefined globals must not be found")
+	}
+
+	_, err := vm.Eval(ctx, "\n  nope;")
+	var located *exception.Error
+	if !errors.As(err, &located) || located.Line != 2 || located.Column != 3 {
+		t.Fatalf("runtime errors must be located. got=%#v", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := vm.Eval(cancelled, "print 1;"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("a cancelled context must not run. got=%v", err)
+	}
+}
+
+func TestCallback(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	vm := NewVM(VMOptions{Stdout: stdout})
+	ctx := context.Background()
+
+	handlers := map[string]*Callback{}
+	vm.Set("on", func(event string, handler Value) error {
+		cb, err := vm.CallbackOf(handler)
+		handlers[event] = cb
+		return err
+	})
+	vm.Set("each", func(fn Value) error {
+		cb, err := vm.CallbackOf(fn)
+		if err != nil {
+			return err
+		}
+		for n := 1; n <= 2; n++ {
+			if _, err := cb.Call(ctx, n); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	_, err := vm.Eval(ctx, `
+		let total = 0;
+		fun onOrder(amount) { total = total + amount; }
+		fun fail(x) { print -x; }
+		fun show(n) { print n; }
+		on("order", onOrder);
+		{ let local = "kept"; each(show); print local; }`)
+	if err != nil {
+		t.Fatalf("unexpected error. got=%v", err)
+	}
+	if stdout.String() != "1\n2\nkept\n" {
+		t.Fatalf("calling back into Lox must not change the environment of the caller. got=%q", stdout.String())
+	}
+
+	for _, amount := range []any{12, 3.5} {
+		if _, err := handlers["order"].Call(ctx, amount); err != nil {
+			t.Fatalf("unexpected error. got=%v", err)
+		}
+	}
+	if total, _ := vm.Get("total"); total.AsNumber() != 15.5 {
+		t.Fatalf("the handler must update the globals. got=%v", total)
+	}
+
+	fail, err := vm.Callback("fail")
+	if err != nil {
+		t.Fatalf("unexpected error. got=%v", err)
+	}
+	var located *exception.Error
+	if _, err := fail.Call(ctx, "x"); !errors.Is(err, ErrRuntime) || !errors.As(err, &located) || located.Line != 4 {
+		t.Fatalf("errors of the function must be located runtime errors. got=%v", err)
+	}
+	if _, err := fail.Call(ctx); !errors.Is(err, ErrRuntime) {
+		t.Fatalf("calls with the wrong number of arguments must fail. got=%v", err)
+	}
+	if _, err := vm.Eval(ctx, `print "still running";`); err != nil {
+		t.Fatalf("the VM must still be usable after a failed call. got=%v", err)
+	}
+
+	for _, name := range []string{"total", "missing"} {
+		if _, err := vm.Callback(name); err == nil {
+			t.Fatalf("%s cannot be called", name)
+		}
+	}
+}
+
+func TestVMCapabilities(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.txt")
+	os.WriteFile(path, []byte("rule"), 0644)
+	ctx := context.Background()
+
+	vm := NewVM(VMOptions{})
+	vm.Set("path", path)
+	if _, err := vm.Eval(ctx, `readFile(path);`); !errors.Is(err, ErrRuntime) || !errors.Is(err, capability.ErrDenied) {
+		t.Fatalf("an embedded script must not read files by default. got=%v", err)
+	}
+
+	vm = NewVM(VMOptions{Capabilities: capability.Set{Read: capability.Scope{Only: []string{path}}}})
+	vm.Set("path", path)
+	if got, err := vm.Eval(ctx, `readFile(path);`); err != nil || got.AsString() != "rule" {
+		t.Fatalf("the file must be read once allowed. got=%v err=%v", got, err)
+	}
+}
+
+func TestVMLimits(t *testing.T) {
+	vm := NewVM(VMOptions{Limits: Limits{Statements: 500}})
+	ctx := context.Background()
+
+	_, err := vm.Eval(ctx, "let n = 0;\nwhile (true) { n = n + 1; }")
+	if !errors.Is(err, ErrRuntime) || !errors.Is(err, exception.ErrStatementLimit) || !exception.IsLimit(err) {
+		t.Fatalf("expected the statement limit to stop the script. got=%v", err)
+	}
+	// The budget is per call, the VM can still be used.
+	if got, err := vm.Eval(ctx, "n > 0;"); err != nil || !got.AsBool() {
+		t.Fatalf("unexpected result. got=%v err=%v", got, err)
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	vm = NewVM(VMOptions{})
+	if _, err := vm.Eval(timeout, "while (true) {}"); !errors.Is(err, context.DeadlineExceeded) || !exception.IsLimit(err) {
+		t.Fatalf("expected the context to stop the script. got=%v", err)
+	}
+}
+
+func TestModules(t *testing.T) {
+	dir := t.TempDir()
+	lib := t.TempDir()
+	t.Setenv(LOX_PATH, lib)
+	files := map[string]string{
+		"lib/math.lox": "print \"loading math\";\nlet pi = 3;\nlet _scale = 2;\nfun double(x) { print x * _scale; }\nfun fail() { print nope; }\n",
+		"lib/util.lox": "from \"math.lox\" import pi;\nlet tau = pi * 2;\n",
+		"a.lox":        "import \"b.lox\" as b;\n",
+		"b.lox":        "import \"a.lox\" as a;\n",
+	}
+	for name, code := range files {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755)
+		os.WriteFile(filepath.Join(dir, name), []byte(code), 0644)
+	}
+	os.WriteFile(filepath.Join(lib, "strings.lox"), []byte("let sep = \", \";\n"), 0644)
+
+	tests := []struct {
+		code string
+		want string
+		err  string
+	}{
+		{code: `import "lib/math.lox" as m; print m.pi;`, want: "loading math\n3\n"},
+		{code: `import "lib/math.lox" as m; from "lib/math.lox" import pi; import "lib/util.lox" as u; print pi + u.tau;`, want: "loading math\n9\n"},
+		{code: `import "strings.lox" as s; print s.sep;`, want: ", \n"},
+		{code: `let from = 1; import "lib/math.lox" as as; print as.pi + from;`, want: "loading math\n4\n"},
+		{code: `import "lib/math.lox" as m; let r = m.double(4);`, want: "loading math\n8\n"},
+		{code: `let _scale = 100; from "lib/math.lox" import double; let r = double(4);`, want: "loading math\n8\n"},
+		{code: `let pi = 100; import "lib/util.lox" as u; print u.tau;`, want: "loading math\n6\n"},
+		{code: `import "lib/math.lox" as m; let r = m.fail();`, err: filepath.Join(dir, "lib", "math.lox") + ":5:20: unhandled exception"},
+		{code: `import "lib/math.lox" as m; print m._scale;`, err: "does not export '_scale'"},
+		{code: `from "lib/math.lox" import e;`, err: "does not export 'e'"},
+		{code: `import "lib/math.lox" as m; m.pi = 4;`, err: "modules are read-only"},
+		{code: `import "lib/nope.lox" as m;`, err: `cannot find module "lib/nope.lox"`},
+		{code: `import "a.lox" as a;`, err: "import cycle: " + filepath.Join(dir, "a.lox") + " -> " + filepath.Join(dir, "b.lox") + " -> " + filepath.Join(dir, "a.lox")},
+	}
+
+	for _, test := range tests {
+		main := filepath.Join(dir, "main.lox")
+		os.WriteFile(main, []byte(test.code), 0644)
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		err := NewRunner(stderr, stdout, Options{Capabilities: capability.Set{Read: capability.Scope{Only: []string{dir, lib}}}}).RunFile(main)
+		if test.err != "" {
+			if !errors.Is(err, ErrRuntime) || !strings.Contains(stderr.String(), test.err) {
+				t.Fatalf("%s -> wrong error. want contains=%q got=%v", test.code, test.err, err)
+			}
+			continue
+		}
+		if err != nil || stdout.String() != test.want {
+			t.Fatalf("%s -> wrong output. want=%q got=%q err=%v", test.code, test.want, stdout.String(), err)
+		}
+	}
+
+	// Modules are files like the ones read by readFile(), the same capability is needed.
+	main := filepath.Join(dir, "main.lox")
+	for _, code := range []string{`import "lib/math.lox" as m;`, `import "strings.lox" as s;`, `print readFile("lib/math.lox");`} {
+		os.WriteFile(main, []byte(code), 0644)
+		stderr := &bytes.Buffer{}
+		err := NewRunner(stderr, &bytes.Buffer{}, Options{}).RunFile(main)
+		if !errors.Is(err, ErrRuntime) || !strings.Contains(stderr.String(), "permission denied: read access") {
+			t.Fatalf("%s -> must be denied without the read capability. got=%v stderr=%q", code, err, stderr.String())
+		}
+	}
+}
+
+func TestVMModules(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "greet.lox")
+	os.WriteFile(path, []byte("let greeting = \"hello\";"), 0644)
+	ctx := context.Background()
+
+	vm := NewVM(VMOptions{})
+	vm.Set("path", path)
+	if _, err := vm.Eval(ctx, `import "`+path+`" as g;`); !errors.Is(err, capability.ErrDenied) {
+		t.Fatalf("an embedded script must not import modules by default. got=%v", err)
+	}
+	// Missing files are denied the same way, a script cannot tell which ones exist.
+	if _, err := vm.Eval(ctx, `import "missing.lox" as g;`); !errors.Is(err, capability.ErrDenied) {
+		t.Fatalf("looking for a module must be denied too. got=%v", err)
+	}
+
+	vm = NewVM(VMOptions{Capabilities: capability.Set{Read: capability.Scope{Only: []string{dir}}}})
+	if got, err := vm.Eval(ctx, `from "`+path+`" import greeting; greeting;`); err != nil || got.AsString() != "hello" {
+		t.Fatalf("the module must be imported once allowed. got=%v err=%v", got, err)
+	}
+
+	// The directories of LOX_PATH are searched even though the working directory cannot be read.
+	lib := filepath.Join(dir, "lib")
+	os.Mkdir(lib, 0755)
+	os.WriteFile(filepath.Join(lib, "m.lox"), []byte("let answer = 42;"), 0644)
+	t.Setenv(LOX_PATH, lib)
+	vm = NewVM(VMOptions{Capabilities: capability.Set{Read: capability.Scope{Only: []string{lib}}}})
+	if got, err := vm.Eval(ctx, `import "m.lox" as m; m.answer;`); err != nil || got.AsNumber() != 42 {
+		t.Fatalf("the module must be found in the allowed LOX_PATH. got=%v err=%v", got, err)
+	}
+	if _, err := vm.Eval(ctx, `import "nope.lox" as m;`); !errors.Is(err, capability.ErrDenied) {
+		t.Fatalf("a missing module must be denied when a candidate is. got=%v", err)
+	}
+
+	// A module counts against the limits of the script importing it.
+	busy := filepath.Join(dir, "busy.lox")
+	os.WriteFile(busy, []byte("let n = 0; while (n < 100) { n = n + 1; }"), 0644)
+	loop := "let k = 0; while (k < 100) { k = k + 1; }"
+	limits := Limits{Statements: 300}
+	vm = NewVM(VMOptions{Limits: limits, Capabilities: capability.Set{Read: capability.Scope{Only: []string{dir}}}})
+	if _, err := vm.Eval(ctx, loop); err != nil {
+		t.Fatalf("the loop alone must be within the limits. got=%v", err)
+	}
+	if _, err := vm.Eval(ctx, `import "`+busy+`" as b; `+loop); !errors.Is(err, exception.ErrStatementLimit) {
+		t.Fatalf("the module must share the budget of the script. got=%v", err)
+	}
+}
+
+func TestProgramConcurrentRuns(t *testing.T) {
+	prog, err := Compile(source.New("rules.lox", `
+		let total = 0;
+		fun add(n) { total = total + n; }
+		for (let i = 0; i < 100; i = i + 1) {
+			let amount = i * factor;
+			{ add(amount); }
+		}
+		total;`), true)
+	if err != nil {
+		t.Fatalf("unexpected error. got=%v", err)
+	}
+
+	var wg sync.WaitGroup
+	results := make([]Value, 16)
+	errs := make([]error, len(results))
+	for n := range results {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			vm := NewVM(VMOptions{})
+			vm.Set("factor", n)
+			results[n], errs[n] = vm.Run(context.Background(), prog)
+		}(n)
+	}
+	wg.Wait()
+
+	for n, res := range results {
+		if want := float64(4950 * n); errs[n] != nil || res.AsNumber() != want {
+			t.Fatalf("run %d: wrong result. want=%v got=%v err=%v", n, want, res, errs[n])
+		}
+	}
+
+	_, err = NewVM(VMOptions{}).Run(context.Background(), prog)
+	var located *exception.Error
+	if !errors.As(err, &located) || located.Source != "rules.lox" {
+		t.Fatalf("errors must be located in the program's source. got=%v", err)
+	}
+	if _, err := Compile(source.New("broken.lox", "let = 1;"), false); !errors.Is(err, ErrSyntax) {
+		t.Fatalf("expected a syntax error. got=%v", err)
+	}
+}
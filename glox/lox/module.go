@@ -0,0 +1,158 @@
+package lox
+
+import (
+	"fmt"
+	"glox/interpreter"
+	"glox/resolver"
+	"glox/source"
+	"glox/token"
+	"glox/value"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LOX_PATH lists the directories searched for the modules that are not found next to the importing file,
+// separated like the PATH.
+const LOX_PATH = "LOX_PATH"
+
+// Loads the modules imported by a program and the modules they import. Each module runs once in its own
+// interpreter, importing it again returns the same module.
+type modules struct {
+	optimized bool
+	// Returns an interpreter configured like the importing program's, without an importer.
+	newInterpreter func() *interpreter.Interpreter
+	// Called with every path a module is looked for at before the filesystem is touched, e.g. to check the
+	// capabilities of a VM.
+	check func(path string) error
+
+	loaded  map[string]*module // By absolute path.
+	loading []string           // Names of the programs being run, the innermost last.
+}
+
+func newModules(optimized bool, newInterpreter func() *interpreter.Interpreter, check func(string) error) *modules {
+	return &modules{optimized: optimized, newInterpreter: newInterpreter, check: check, loaded: map[string]*module{}}
+}
+
+func (m *modules) Import(importer *interpreter.Interpreter, path string) (interpreter.Object, error) {
+	from := importer.Source
+	name, err := m.find(from, path)
+	if err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return nil, err
+	}
+	if mod, isOk := m.loaded[abs]; isOk {
+		return mod, nil
+	}
+
+	if len(m.loading) == 0 && isFile(from) {
+		// The program importing the first module takes part in the cycles too.
+		m.loading = []string{from}
+		defer func() { m.loading = nil }()
+	}
+	for n, loading := range m.loading {
+		if same(loading, abs) {
+			return nil, fmt.Errorf("import cycle: %s -> %s", strings.Join(m.loading[n:], " -> "), name)
+		}
+	}
+
+	src, err := source.Load(name)
+	if err != nil {
+		return nil, err
+	}
+	stmts, err := compile(src, m.optimized)
+	if err != nil {
+		return nil, err
+	}
+
+	glox := m.newInterpreter()
+	glox.Source = name
+	glox.Importer = m
+	builtins := len(glox.Env.Names())
+
+	m.loading = append(m.loading, name)
+	resolver.New().Resolve(stmts)
+	_, err = glox.EvalWithin(importer, stmts)
+	m.loading = m.loading[:len(m.loading)-1]
+	if err != nil {
+		return nil, err
+	}
+
+	mod := &module{name: name, glox: glox, exports: map[string]bool{}}
+	for _, global := range glox.Env.Names()[builtins:] {
+		if !strings.HasPrefix(global, "_") {
+			mod.exports[global] = true
+		}
+	}
+	m.loaded[abs] = mod
+	return mod, nil
+}
+
+// Paths are relative to the directory of the importing file, then to the directories of LOX_PATH. Programs
+// that are not read from a file import relatively to the working directory. Every candidate is checked before
+// the filesystem is touched and the denied ones are skipped. When the module is not found, the first denial
+// is returned, so that a script cannot tell which files exist where it may not read.
+func (m *modules) find(from string, path string) (string, error) {
+	dirs := []string{""}
+	if !filepath.IsAbs(path) {
+		dir := "."
+		if isFile(from) {
+			dir = filepath.Dir(from)
+		}
+		dirs = append([]string{dir}, filepath.SplitList(os.Getenv(LOX_PATH))...)
+	}
+	var denied error
+	for _, dir := range dirs {
+		name := filepath.Join(dir, path)
+		if m.check != nil {
+			if err := m.check(name); err != nil {
+				if denied == nil {
+					denied = err
+				}
+				continue
+			}
+		}
+		if info, err := os.Stat(name); err == nil && !info.IsDir() {
+			return name, nil
+		}
+	}
+	if denied != nil {
+		return "", denied
+	}
+	return "", fmt.Errorf("cannot find module %q.", path)
+}
+
+func isFile(name string) bool {
+	return name != "" && name != source.STDIN && name != source.EVAL
+}
+
+func same(name string, abs string) bool {
+	other, err := filepath.Abs(name)
+	return err == nil && other == abs
+}
+
+// A module is an object whose properties are the globals defined by its code, except the builtins and the
+// names starting with an underscore, which are private. Scripts cannot assign them.
+type module struct {
+	name    string
+	glox    *interpreter.Interpreter
+	exports map[string]bool
+}
+
+func (mod *module) Get(name string) (value.Value, error) {
+	if !mod.exports[name] {
+		return value.Nil, fmt.Errorf("module '%s' does not export '%s'.", mod.name, name)
+	}
+	return mod.glox.Env.Get(token.Token{Type: token.IDENTIFIER, Lexeme: name})
+}
+
+func (mod *module) Set(name string, val value.Value) error {
+	return fmt.Errorf("cannot assign '%s' of module '%s', modules are read-only.", name, mod.name)
+}
+
+func (mod *module) String() string {
+	return fmt.Sprintf("<module '%s'>", mod.name)
+}
@@ -0,0 +1,43 @@
+package lox
+
+import (
+	"context"
+	"fmt"
+	"glox/ast"
+	"glox/resolver"
+	"glox/source"
+	"glox/value"
+)
+
+// Program is a compiled script: parsed, optimized when asked and resolved. It is never modified afterwards,
+// so that a program compiled once can be run by many VMs at the same time, one per goroutine.
+type Program struct {
+	name  string
+	stmts []ast.Statement
+}
+
+// Compile prepares a script to be run with `VM.Run`. Errors wrap ErrSyntax and are not printed.
+func Compile(src *source.Source, optimized bool) (*Program, error) {
+	stmts, err := compile(src, optimized)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSyntax, err)
+	}
+	resolver.New().Resolve(stmts)
+	return &Program{name: src.Name, stmts: stmts}, nil
+}
+
+func (p *Program) Name() string {
+	return p.name
+}
+
+// Run runs a compiled program in the VM, like `Eval`. Its errors are located in the program's source.
+func (vm *VM) Run(ctx context.Context, prog *Program) (Value, error) {
+	vm.glox.Source = prog.name
+	defer func() { vm.glox.Source = source.EVAL }()
+
+	res, err := vm.glox.EvalResolved(ctx, prog.stmts)
+	if err != nil {
+		return value.Nil, fmt.Errorf("%w: %w", ErrRuntime, err)
+	}
+	return res, nil
+}
@@ -0,0 +1,271 @@
+package lox
+
+import (
+	"bufio"
+	"fmt"
+	"glox/ast"
+	"glox/exception"
+	"glox/interpreter"
+	"glox/lexer"
+	"glox/lineedit"
+	"glox/parser"
+	"glox/source"
+	"glox/token"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	PROMPT              = ">> "
+	CONTINUATION_PROMPT = ".. "           // Shown while the statement being typed is not complete.
+	HISTORY_FILE        = ".glox_history" // Lines typed in the REPL, in the home directory.
+)
+
+type metaCommand struct {
+	name    string
+	args    string
+	summary string
+	run     func(s *session, arg string)
+}
+
+// Commands typed in the REPL to inspect the session rather than run code, e.g. `:env`.
+var metaCommands []metaCommand
+
+func init() {
+	metaCommands = []metaCommand{
+		{"help", "", "list the REPL commands", (*session).help},
+		{"env", "", "list the global variables and their values", (*session).env},
+		{"load", "<file>", "run a script in the current session", (*session).load},
+		{"reset", "", "forget every variable defined in the session", (*session).reset},
+		{"ast", "<code>", "print the syntax tree of the code as JSON", (*session).ast},
+		{"tokens", "<code>", "print the tokens of the code", (*session).tokens},
+		{"time", "<code>", "run the code and print how long it took", (*session).time},
+	}
+}
+
+// A REPL session, variables defined in a statement are available to the next ones.
+type session struct {
+	*Lox
+	glox *interpreter.Interpreter
+}
+
+// StartREPL runs the statements read from stdin. A statement can span several lines: lines are gathered
+// until they form a complete program, an empty line runs them anyway so that the error is reported.
+// Lines starting with `:` are commands of the REPL itself, `:help` lists them.
+//
+// When stdin is a terminal lines can be edited and recalled from the history, which is kept in
+// `~/.glox_history` across sessions. Otherwise, e.g. when the input is piped, no prompt is shown so that
+// only the output of the program is written.
+func (r *Lox) StartREPL(stdin io.Reader) {
+	s := &session{Lox: r, glox: r.interpreter()}
+	input := s.lineReader(stdin)
+	lines := []string{}
+
+	for {
+		prompt := s.prompt(len(lines) > 0)
+		line, err := input.ReadLine(prompt)
+		if err == lineedit.ErrInterrupted {
+			lines = lines[:0]
+			continue
+		} else if err != nil {
+			if len(lines) > 0 {
+				s.run(source.New("", strings.Join(lines, "\n")), s.glox)
+			}
+			return
+		}
+
+		isBlank := strings.TrimSpace(line) == ""
+		if len(lines) == 0 && isBlank {
+			continue
+		}
+		if len(lines) == 0 && strings.HasPrefix(strings.TrimSpace(line), ":") {
+			s.command(strings.TrimSpace(line))
+			continue
+		}
+		if !isBlank {
+			lines = append(lines, line)
+		}
+
+		code := strings.Join(lines, "\n")
+		if !isBlank && incomplete(code) {
+			continue
+		}
+		s.run(source.New("", code), s.glox)
+		lines = lines[:0]
+	}
+}
+
+func (s *session) prompt(isContinuation bool) string {
+	if isContinuation {
+		if s.opts.ContinuationPrompt != "" {
+			return s.opts.ContinuationPrompt
+		}
+		return CONTINUATION_PROMPT
+	}
+	if s.opts.Prompt != "" {
+		return s.opts.Prompt
+	}
+	return PROMPT
+}
+
+// Runs a meta-command, e.g. `:load script.lox`.
+func (s *session) command(line string) {
+	name, arg, _ := strings.Cut(line[1:], " ")
+	arg = strings.TrimSpace(arg)
+	for _, cmd := range metaCommands {
+		if cmd.name != name {
+			continue
+		}
+		if cmd.args != "" && arg == "" {
+			fmt.Fprintf(s.stdErr, "usage: :%s %s\n", cmd.name, cmd.args)
+			return
+		}
+		cmd.run(s, arg)
+		return
+	}
+	fmt.Fprintf(s.stdErr, "unknown command ':%s', type :help to list the commands.\n", name)
+}
+
+func (s *session) help(string) {
+	for _, cmd := range metaCommands {
+		fmt.Fprintf(s.stdout, "  %-16s %s\n", ":"+strings.TrimSpace(cmd.name+" "+cmd.args), cmd.summary)
+	}
+}
+
+func (s *session) env(string) {
+	for _, name := range s.glox.Env.Names() {
+		val, _ := s.glox.Env.Get(token.Token{Type: token.IDENTIFIER, Lexeme: name})
+		display := val.String()
+		if val.IsString() {
+			display = strconv.Quote(display)
+		}
+		fmt.Fprintf(s.stdout, "%s = %s\n", name, display)
+	}
+}
+
+func (s *session) load(path string) {
+	src, err := source.Load(path)
+	if err != nil {
+		fmt.Fprintf(s.stdErr, "%v\n", err)
+		return
+	}
+	s.glox.Source = src.Name
+	defer func() { s.glox.Source = "" }()
+	s.run(src, s.glox)
+}
+
+func (s *session) reset(string) {
+	s.glox = s.interpreter()
+}
+
+func (s *session) ast(code string) {
+	tokens, err := lexer.New(code).Tokenize()
+	if err == nil {
+		var stmts []ast.Statement
+		if stmts, err = parser.New(tokens).Parse(); err == nil {
+			var data []byte
+			if data, err = ast.MarshalProgramIndent(stmts, "  "); err == nil {
+				fmt.Fprintln(s.stdout, string(data))
+				return
+			}
+		}
+	}
+	fmt.Fprintf(s.stdErr, "%v\n", err)
+}
+
+func (s *session) tokens(code string) {
+	tokens, err := lexer.New(code).Tokenize()
+	token.Fprint(s.stdout, tokens)
+	if err != nil {
+		fmt.Fprintf(s.stdErr, "%v\n", err)
+	}
+}
+
+func (s *session) time(code string) {
+	start := time.Now()
+	s.run(source.New("", code), s.glox)
+	fmt.Fprintf(s.stdout, "took %s\n", time.Since(start))
+}
+
+// Returns the keywords and the variables of the session starting with prefix, sorted.
+func (s *session) complete(prefix string) []string {
+	words := []string{}
+	seen := map[string]bool{}
+	for _, word := range append(token.Keywords(), s.glox.Env.Names()...) {
+		if strings.HasPrefix(word, prefix) && !seen[word] {
+			seen[word] = true
+			words = append(words, word)
+		}
+	}
+	sort.Strings(words)
+	return words
+}
+
+type lineReader interface {
+	// Returns io.EOF once there are no more lines, and lineedit.ErrInterrupted when the line is abandoned.
+	ReadLine(prompt string) (string, error)
+}
+
+func (s *session) lineReader(stdin io.Reader) lineReader {
+	if f, isFile := stdin.(*os.File); !isFile || !lineedit.IsTerminal(f) {
+		return &scanner{bufio.NewScanner(stdin)}
+	}
+
+	editor := lineedit.New(stdin, s.stdout)
+	editor.Complete = s.complete
+	path := ""
+	if home, err := os.UserHomeDir(); err == nil {
+		path = filepath.Join(home, HISTORY_FILE)
+		editor.LoadHistory(path)
+	}
+	return &history{editor, path}
+}
+
+// Reads lines that are not typed in a terminal, e.g. piped to glox. Nobody is there to read the prompt, so
+// it is not shown.
+type scanner struct {
+	*bufio.Scanner
+}
+
+func (s *scanner) ReadLine(string) (string, error) {
+	if !s.Scan() {
+		if err := s.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return s.Text(), nil
+}
+
+// Saves every line read by the editor to the history file. The history is a convenience, failing to save
+// it does not stop the REPL.
+type history struct {
+	*lineedit.Editor
+	path string
+}
+
+func (h *history) ReadLine(prompt string) (string, error) {
+	line, err := h.Editor.ReadLine(prompt)
+	if err == nil {
+		h.AddHistory(line)
+		if h.path != "" {
+			h.SaveHistory(h.path)
+		}
+	}
+	return line, err
+}
+
+// Tells whether code is the beginning of a program, i.e. it is only invalid because it ends too early.
+func incomplete(code string) bool {
+	tokens, err := lexer.New(code).Tokenize()
+	if err != nil {
+		return exception.IsAtEnd(err)
+	}
+	_, err = parser.New(tokens).Parse()
+	return exception.IsAtEnd(err)
+}
@@ -0,0 +1,174 @@
+package lox
+
+import (
+	"context"
+	"fmt"
+	"glox/capability"
+	"glox/host"
+	"glox/interpreter"
+	"glox/native"
+	"glox/source"
+	"glox/token"
+	"glox/value"
+	"io"
+	"reflect"
+)
+
+// Value is a Lox value, as returned by `VM.Eval` and `VM.Get`.
+type Value = value.Value
+
+// Limits bound the resources a script can use, see `interpreter.Limits`.
+type Limits = interpreter.Limits
+
+type VMOptions struct {
+	Stdout   io.Writer // Where `print` writes, discarded when nil.
+	Stderr   io.Writer // Discarded when nil.
+	Optimize bool      // Runs the `optimize` pass on the code before evaluating it.
+	Limits   Limits    // Applied to every call to `Eval` and `Callback.Call`.
+	// What scripts may do outside the VM, e.g. reading files with `readFile`. A VM denies everything unless it
+	// is granted here.
+	Capabilities capability.Set
+}
+
+// VM runs Lox code on behalf of a Go program. Globals persist across calls to `Eval`, so a VM can be loaded
+// with definitions once and then evaluate many expressions. A VM must not be used by several goroutines at
+// the same time.
+type VM struct {
+	glox *interpreter.Interpreter
+	opts VMOptions
+}
+
+func NewVM(opts VMOptions) *VM {
+	if opts.Stdout == nil {
+		opts.Stdout = io.Discard
+	}
+	if opts.Stderr == nil {
+		opts.Stderr = io.Discard
+	}
+	newInterpreter := func() *interpreter.Interpreter {
+		glox := interpreter.New(opts.Stderr, opts.Stdout)
+		glox.Quiet = true
+		glox.Limits = opts.Limits
+		glox.Capabilities = opts.Capabilities
+		return glox
+	}
+	glox := newInterpreter()
+	glox.Source = source.EVAL
+	// Modules are read like any other file, they must be allowed with the READ capability.
+	glox.Importer = newModules(opts.Optimize, newInterpreter, func(path string) error {
+		return opts.Capabilities.Check(capability.READ, path)
+	})
+	return &VM{glox: glox, opts: opts}
+}
+
+// Eval runs code and returns the value of its last statement when it is an expression, nil otherwise.
+// Errors wrap ErrSyntax or ErrRuntime and an `*exception.Error` locating them, they are not printed. The code
+// is stopped once ctx is done or it goes over the VM's limits, `exception.IsLimit` tells these errors apart.
+func (vm *VM) Eval(ctx context.Context, code string) (Value, error) {
+	if err := ctx.Err(); err != nil {
+		return value.Nil, err
+	}
+
+	prog, err := Compile(source.New(source.EVAL, code), vm.opts.Optimize)
+	if err != nil {
+		return value.Nil, err
+	}
+	return vm.Run(ctx, prog)
+}
+
+// Set defines a global variable. Functions are bound with `native.Func`, structs and maps are exposed with
+// `host.New` and the other values are converted with `ValueOf`.
+func (vm *VM) Set(name string, val any) error {
+	converted, err := vm.value(name, val)
+	if err != nil {
+		return err
+	}
+	vm.glox.Env.Define(name, converted)
+	return nil
+}
+
+func (vm *VM) value(name string, val any) (Value, error) {
+	switch v := val.(type) {
+	case interpreter.Callable, interpreter.Object:
+		return value.Object(v.(fmt.Stringer)), nil
+	}
+
+	switch rv := reflect.ValueOf(val); {
+	case rv.Kind() == reflect.Func:
+		fn, err := native.Func[*interpreter.Interpreter](name, val)
+		if err != nil {
+			return value.Nil, err
+		}
+		return value.Object(fn), nil
+	case rv.Kind() == reflect.Struct, rv.Kind() == reflect.Map, rv.Kind() == reflect.Pointer && rv.Elem().Kind() == reflect.Struct:
+		obj, err := host.New(val, host.Options{})
+		if err != nil {
+			return value.Nil, err
+		}
+		return value.Object(obj), nil
+	}
+	return ValueOf(val)
+}
+
+// Get returns the value of a global variable, false when it is not defined.
+func (vm *VM) Get(name string) (Value, bool) {
+	val, err := vm.glox.Env.Get(token.Token{Type: token.IDENTIFIER, Lexeme: name})
+	return val, err == nil
+}
+
+// Callback returns a handle to call the function stored in a global variable from Go.
+func (vm *VM) Callback(name string) (*Callback, error) {
+	val, isOk := vm.Get(name)
+	if !isOk {
+		return nil, fmt.Errorf("undefined variable '%s'", name)
+	}
+	return vm.CallbackOf(val)
+}
+
+// CallbackOf returns a handle to call a function received from Lox, e.g. the argument of a Go function a
+// script calls to register an event handler.
+func (vm *VM) CallbackOf(val Value) (*Callback, error) {
+	fn, isOk := val.AsObject().(interpreter.Callable)
+	if !isOk {
+		return nil, fmt.Errorf("%s '%s' cannot be called", val.Kind(), val)
+	}
+	return &Callback{vm: vm, fn: fn}, nil
+}
+
+// Callback is a Lox function that Go code can call, it is valid as long as its VM.
+type Callback struct {
+	vm *VM
+	fn interpreter.Callable
+}
+
+// Call converts the Go arguments like `VM.Set` and calls the function. Errors raised by the function wrap
+// ErrRuntime. It can be called from a Go function that Lox code is calling, not from another goroutine while
+// the VM is running.
+func (c *Callback) Call(ctx context.Context, args ...any) (Value, error) {
+	if err := ctx.Err(); err != nil {
+		return value.Nil, err
+	}
+
+	converted := make([]value.Value, len(args))
+	for n, arg := range args {
+		var err error
+		if converted[n], err = c.vm.value(fmt.Sprintf("%s argument %d", c.fn, n+1), arg); err != nil {
+			return value.Nil, err
+		}
+	}
+	res, err := c.vm.glox.Call(ctx, c.fn, converted)
+	if err != nil {
+		return value.Nil, fmt.Errorf("%w: %w", ErrRuntime, err)
+	}
+	return res, nil
+}
+
+func (c *Callback) String() string {
+	return c.fn.String()
+}
+
+// ValueOf converts a Go value into a Lox value: nil, booleans, numbers of any type, strings, Values and
+// `fmt.Stringer`s, which become objects.
+func ValueOf(val any) (Value, error) {
+	return native.ToValue(val)
+}
@@ -1,23 +1,10 @@
 package main
 
 import (
-	"fmt"
-	"glox/lox"
+	"glox/cli"
 	"os"
 )
 
 func main() {
-	args := os.Args[1:]
-	runner := lox.NewRunner(os.Stderr, os.Stdout)
-
-	if len(args) < 1 {
-		runner.StartREPL(os.Stdin)
-
-	} else if len(args) == 1 {
-		runner.RunFile(args[0])
-
-	} else {
-		fmt.Printf("Usage: glox [script]\n")
-		os.Exit(64)
-	}
+	os.Exit(cli.Main(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 }
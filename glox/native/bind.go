@@ -0,0 +1,149 @@
+package native
+
+import (
+	"fmt"
+	"glox/value"
+	"math"
+	"reflect"
+)
+
+var (
+	valueType = reflect.TypeOf(value.Value{})
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// Func binds a Go function to Lox, e.g. `func(string, float64) (bool, error)`. Its arity is the number of
+// parameters, the arguments are converted to the parameter types and the results back with `ToValue`. The
+// function may return nothing, a value, an error or a value and an error. Errors, including arguments that
+// cannot be converted, fail the call.
+func Func[T any](name string, fn any) (*native[T], error) {
+	fv := reflect.ValueOf(fn)
+	if fv.Kind() != reflect.Func || fv.IsNil() {
+		return nil, fmt.Errorf("%s: %T is not a function", name, fn)
+	}
+	ft := fv.Type()
+	if ft.IsVariadic() {
+		return nil, fmt.Errorf("%s: variadic functions cannot be bound", name)
+	}
+	returnsErr := ft.NumOut() > 0 && ft.Out(ft.NumOut()-1) == errorType
+	if ft.NumOut() > 2 || (ft.NumOut() == 2 && !returnsErr) {
+		return nil, fmt.Errorf("%s: %s must return at most a value and an error", name, ft)
+	}
+
+	return &native[T]{
+		arity: ft.NumIn(),
+		call: func(i T, argumets []value.Value) (value.Value, error) {
+			in := make([]reflect.Value, len(argumets))
+			for n, arg := range argumets {
+				converted, err := FromValue(arg, ft.In(n))
+				if err != nil {
+					return value.Nil, fmt.Errorf("%s() expects %s as argument %d, got %s '%s'.", name, err, n+1, arg.Kind(), arg)
+				}
+				in[n] = converted
+			}
+
+			out := fv.Call(in)
+			if returnsErr {
+				if err, _ := out[len(out)-1].Interface().(error); err != nil {
+					return value.Nil, err
+				}
+				out = out[:len(out)-1]
+			}
+			if len(out) == 0 {
+				return value.Nil, nil
+			}
+			return ToValue(out[0].Interface())
+		},
+	}, nil
+}
+
+// ToValue converts a Go value into a Lox value: nil, booleans, numbers of any type, strings, Values and
+// `fmt.Stringer`s, which become objects. Types of a basic kind stay numbers, booleans or strings even when
+// they are Stringers, e.g. `time.Duration`.
+func ToValue(val any) (value.Value, error) {
+	switch v := val.(type) {
+	case nil, value.Value, bool, float64, int, string:
+		return value.Of(v), nil
+	}
+
+	rv := reflect.ValueOf(val)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Number(float64(rv.Int())), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return value.Number(float64(rv.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		return value.Number(rv.Float()), nil
+	case reflect.Bool:
+		return value.Bool(rv.Bool()), nil
+	case reflect.String:
+		return value.String(rv.String()), nil
+	}
+	if v, isOk := val.(fmt.Stringer); isOk {
+		return value.Of(v), nil
+	}
+	return value.Nil, fmt.Errorf("%T cannot be converted to a Lox value", val)
+}
+
+// Wrapper is implemented by the Lox objects wrapping a Go value, e.g. host objects. They are unwrapped when
+// they are passed back to Go.
+type Wrapper interface {
+	Unwrap() reflect.Value
+}
+
+// FromValue converts a Lox value to the Go type t. The error describes the expected value, e.g. "an integer".
+func FromValue(val value.Value, t reflect.Type) (reflect.Value, error) {
+	if t == valueType {
+		return reflect.ValueOf(val), nil
+	}
+	if wrapper, isOk := val.AsObject().(Wrapper); isOk {
+		inner := wrapper.Unwrap()
+		if inner.Type().AssignableTo(t) {
+			return inner, nil
+		} else if inner.Kind() == reflect.Pointer && inner.Type().Elem().AssignableTo(t) {
+			return inner.Elem(), nil
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		if val.IsString() {
+			return reflect.ValueOf(val.AsString()).Convert(t), nil
+		}
+		return reflect.Value{}, fmt.Errorf("a string")
+	case reflect.Bool:
+		if val.Kind() == value.BOOL {
+			return reflect.ValueOf(val.AsBool()).Convert(t), nil
+		}
+		return reflect.Value{}, fmt.Errorf("a boolean")
+	case reflect.Float32, reflect.Float64:
+		if val.IsNumber() {
+			return reflect.ValueOf(val.AsNumber()).Convert(t), nil
+		}
+		return reflect.Value{}, fmt.Errorf("a number")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		num := val.AsNumber()
+		if val.IsNumber() && num == math.Trunc(num) && math.Abs(num) < 1<<63 && !reflect.Zero(t).OverflowInt(int64(num)) {
+			return reflect.ValueOf(int64(num)).Convert(t), nil
+		}
+		return reflect.Value{}, fmt.Errorf("an integer")
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		num := val.AsNumber()
+		if val.IsNumber() && num >= 0 && num == math.Trunc(num) && num < 1<<64 && !reflect.Zero(t).OverflowUint(uint64(num)) {
+			return reflect.ValueOf(uint64(num)).Convert(t), nil
+		}
+		return reflect.Value{}, fmt.Errorf("a positive integer")
+	case reflect.Interface:
+		if val.IsNil() {
+			return reflect.Zero(t), nil
+		}
+		if goVal := reflect.ValueOf(val.Interface()); goVal.Type().Implements(t) {
+			return goVal, nil
+		}
+	default:
+		if obj := val.AsObject(); obj != nil && reflect.TypeOf(obj).AssignableTo(t) {
+			return reflect.ValueOf(obj), nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("a %s", t)
+}
@@ -1,16 +1,21 @@
 package native
 
-import "time"
+import (
+	"fmt"
+	"glox/value"
+	"math"
+	"time"
+)
 
 const NATIVE_FN_STR = "<native fn>"
 
 type native[T any] struct {
-	call     func(i T, argumets []any) any
+	call     func(i T, argumets []value.Value) (value.Value, error)
 	arity    int
 	toString string
 }
 
-func (n *native[T]) Call(i T, args []any) any {
+func (n *native[T]) Call(i T, args []value.Value) (value.Value, error) {
 	return n.call(i, args)
 }
 
@@ -25,11 +30,38 @@ func (n *native[T]) String() string {
 	return n.toString
 }
 
-func Clock[T any]() native[T] {
-	return native[T]{
+func Clock[T any]() *native[T] {
+	return &native[T]{
 		arity: 0,
-		call: func(i T, argumets []any) any {
-			return float64(time.Now().UnixNano()) / float64(time.Second)
+		call: func(i T, argumets []value.Value) (value.Value, error) {
+			return value.Number(float64(time.Now().UnixNano()) / float64(time.Second)), nil
+		},
+	}
+}
+
+// Argc returns the number of arguments passed to the script.
+func Argc[T any](args []string) *native[T] {
+	return &native[T]{
+		arity: 0,
+		call: func(i T, argumets []value.Value) (value.Value, error) {
+			return value.Number(float64(len(args))), nil
+		},
+	}
+}
+
+// Args returns the argument passed to the script at the given index, starting from 0.
+func Args[T any](args []string) *native[T] {
+	return &native[T]{
+		arity: 1,
+		call: func(i T, argumets []value.Value) (value.Value, error) {
+			index := argumets[0]
+			if !index.IsNumber() || index.AsNumber() != math.Trunc(index.AsNumber()) {
+				return value.Nil, fmt.Errorf("args() expects an integer index, got '%s'.", index.String())
+			}
+			if n := int(index.AsNumber()); n >= 0 && n < len(args) {
+				return value.String(args[n]), nil
+			}
+			return value.Nil, fmt.Errorf("argument index %s out of range, the script received %d arguments.", index.String(), len(args))
 		},
 	}
 }
@@ -1,20 +1,27 @@
 package native
 
 import (
+	"context"
+	"errors"
+	"glox/capability"
+	"glox/value"
 	"math"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
 
 func TestClock(t *testing.T) {
 	clock := Clock[any]()
-	got := clock.call(nil, []any{})
+	got, err := clock.call(nil, []value.Value{})
 	want := (float64(time.Now().UnixNano()) / float64(time.Millisecond)) / 1000
 
-	val, isOk := got.(float64)
-	if !isOk {
-		t.Fatalf("clock time is not a float64. got='%T'", got)
+	if err != nil || !got.IsNumber() {
+		t.Fatalf("clock time is not a number. got='%v'", got.Kind())
 	}
+	val := got.AsNumber()
 
 	margin := math.Round((want/val)*100) / 100
 	if margin > 1 || margin < 0.9 {
@@ -30,3 +37,160 @@ func TestClock(t *testing.T) {
 		t.Errorf("clock.Arity() has wrong value. want='0' got='%d'", clock.Arity())
 	}
 }
+
+func TestArgs(t *testing.T) {
+	script := []string{"input.txt", "--verbose"}
+	argc, args := Argc[any](script), Args[any](script)
+
+	if got, _ := argc.call(nil, []value.Value{}); got.AsNumber() != 2 {
+		t.Fatalf("wrong argument count. want='2' got='%v'", got)
+	}
+
+	tests := []struct {
+		index   value.Value
+		want    string
+		wantErr bool
+	}{
+		{value.Number(0), "input.txt", false},
+		{value.Number(1), "--verbose", false},
+		{value.Number(2), "", true},
+		{value.Number(-1), "", true},
+		{value.Number(0.5), "", true},
+		{value.String("0"), "", true},
+	}
+	for _, test := range tests {
+		got, err := args.call(nil, []value.Value{test.index})
+		if test.wantErr {
+			if err == nil {
+				t.Fatalf("args(%v) expected an error. got='%v'", test.index, got)
+			}
+			continue
+		}
+		if err != nil || got.AsString() != test.want {
+			t.Fatalf("args(%v) has wrong value. want=%q got=%q err='%v'", test.index, test.want, got, err)
+		}
+	}
+}
+
+func TestFunc(t *testing.T) {
+	repeat, err := Func[any]("repeat", func(s string, n int) (string, error) {
+		if n < 0 {
+			return "", errors.New("negative count")
+		}
+		return strings.Repeat(s, n), nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error. got=%v", err)
+	}
+	if repeat.Arity() != 2 {
+		t.Fatalf("wrong arity. want=2 got=%d", repeat.Arity())
+	}
+
+	tests := []struct {
+		args    []value.Value
+		want    value.Value
+		wantErr string
+	}{
+		{[]value.Value{value.String("ab"), value.Number(2)}, value.String("abab"), ""},
+		{[]value.Value{value.String("ab"), value.Number(-1)}, value.Nil, "negative count"},
+		{[]value.Value{value.Number(1), value.Number(2)}, value.Nil, "repeat() expects a string as argument 1, got number '1'."},
+		{[]value.Value{value.String("ab"), value.Number(1.5)}, value.Nil, "repeat() expects an integer as argument 2, got number '1.5'."},
+		{[]value.Value{value.String("ab"), value.Number(1e300)}, value.Nil, "expects an integer"},
+	}
+	for _, test := range tests {
+		got, err := repeat.call(nil, test.args)
+		if test.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("repeat(%v) wrong error. want=%q got=%v", test.args, test.wantErr, err)
+			}
+			continue
+		}
+		if err != nil || !value.Equal(got, test.want) {
+			t.Fatalf("repeat(%v) wrong result. want=%v got=%v err=%v", test.args, test.want, got, err)
+		}
+	}
+
+	signatures := []struct {
+		fn   any
+		args []value.Value
+		want value.Value
+	}{
+		{func() {}, nil, value.Nil},
+		{func() error { return nil }, nil, value.Nil},
+		{func(b bool, f float32) uint8 { return uint8(f) + 1 }, []value.Value{value.Bool(true), value.Number(2)}, value.Number(3)},
+		{func(v value.Value, x any) any { return x }, []value.Value{value.Nil, value.String("x")}, value.String("x")},
+		{func() time.Duration { return 2 * time.Second }, nil, value.Number(2e9)},
+		{func() time.Month { return time.March }, nil, value.Number(3)},
+	}
+	for _, test := range signatures {
+		fn, err := Func[any]("fn", test.fn)
+		if err != nil {
+			t.Fatalf("%T: unexpected error. got=%v", test.fn, err)
+		}
+		if got, err := fn.call(nil, test.args); err != nil || !value.Equal(got, test.want) {
+			t.Fatalf("%T: wrong result. want=%v got=%v err=%v", test.fn, test.want, got, err)
+		}
+	}
+
+	if got, err := ToValue(&strings.Builder{}); err != nil || got.Kind() != value.OBJECT {
+		t.Fatalf("a Stringer that is not a number must become an object. got=%v err=%v", got, err)
+	}
+
+	for _, fn := range []any{nil, 12, func(...int) {}, func() (int, int) { return 0, 0 }} {
+		if _, err := Func[any]("fn", fn); err == nil {
+			t.Fatalf("%T must not be bound", fn)
+		}
+	}
+}
+
+func TestSystem(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	t.Setenv("GLOX_TEST", "yes")
+	allowed := capability.Set{
+		Read:  capability.Scope{Only: []string{dir}},
+		Write: capability.Scope{Only: []string{dir}},
+		Env:   capability.Scope{Only: []string{"GLOX_TEST"}},
+	}
+
+	tests := []struct {
+		name    string
+		fn      *native[sandbox]
+		args    []value.Value
+		want    value.Value
+		wantErr error
+	}{
+		{"write", WriteFile[sandbox](), []value.Value{value.String(path), value.Number(12)}, value.Nil, nil},
+		{"read", ReadFile[sandbox](), []value.Value{value.String(path)}, value.String("12"), nil},
+		{"read outside", ReadFile[sandbox](), []value.Value{value.String("/etc/passwd")}, value.Nil, capability.ErrDenied},
+		{"read missing", ReadFile[sandbox](), []value.Value{value.String(filepath.Join(dir, "nope"))}, value.Nil, os.ErrNotExist},
+		{"getenv", Getenv[sandbox](), []value.Value{value.String("GLOX_TEST")}, value.String("yes"), nil},
+		{"getenv denied", Getenv[sandbox](), []value.Value{value.String("HOME")}, value.Nil, capability.ErrDenied},
+		{"exec denied", Exec[sandbox](), []value.Value{value.String("echo hi")}, value.Nil, capability.ErrDenied},
+	}
+	for _, test := range tests {
+		got, err := test.fn.call(sandbox{allowed}, test.args)
+		if !errors.Is(err, test.wantErr) {
+			t.Fatalf("%s: wrong error. want=%v got=%v", test.name, test.wantErr, err)
+		}
+		if !value.Equal(got, test.want) {
+			t.Fatalf("%s: wrong result. want=%v got=%v", test.name, test.want, got)
+		}
+	}
+
+	if _, err := ReadFile[sandbox]().call(sandbox{}, []value.Value{value.String(path)}); !errors.Is(err, capability.ErrDenied) {
+		t.Fatalf("everything must be denied by default. got=%v", err)
+	}
+	if _, err := ReadFile[sandbox]().call(sandbox{capability.All()}, []value.Value{value.Number(1)}); err == nil {
+		t.Fatalf("readFile() expects a string")
+	}
+}
+
+// Grants the capabilities of a set to the natives, outside of any running program.
+type sandbox struct {
+	capability.Set
+}
+
+func (sandbox) Context() context.Context {
+	return context.Background()
+}
@@ -0,0 +1,120 @@
+package native
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"glox/capability"
+	"glox/value"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Sandbox is the interpreter running the natives that access the system, they fail unless it grants them
+// the capability.
+type Sandbox interface {
+	Check(kind capability.Kind, resource string) error
+	// Context of the running program, the natives that wait, e.g. for a command, stop once it is done.
+	Context() context.Context
+}
+
+// ReadFile returns the content of a file, it requires the read capability.
+func ReadFile[T Sandbox]() *native[T] {
+	return &native[T]{
+		arity: 1,
+		call: func(i T, argumets []value.Value) (value.Value, error) {
+			path, err := stringArg("readFile", argumets[0])
+			if err != nil {
+				return value.Nil, err
+			}
+			if err := i.Check(capability.READ, path); err != nil {
+				return value.Nil, err
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return value.Nil, err
+			}
+			return value.String(string(data)), nil
+		},
+	}
+}
+
+// WriteFile replaces the content of a file, it requires the write capability.
+func WriteFile[T Sandbox]() *native[T] {
+	return &native[T]{
+		arity: 2,
+		call: func(i T, argumets []value.Value) (value.Value, error) {
+			path, err := stringArg("writeFile", argumets[0])
+			if err != nil {
+				return value.Nil, err
+			}
+			if err := i.Check(capability.WRITE, path); err != nil {
+				return value.Nil, err
+			}
+			return value.Nil, os.WriteFile(path, []byte(argumets[1].String()), 0644)
+		},
+	}
+}
+
+// Getenv returns the value of an environment variable or nil when it is not set, it requires the env
+// capability.
+func Getenv[T Sandbox]() *native[T] {
+	return &native[T]{
+		arity: 1,
+		call: func(i T, argumets []value.Value) (value.Value, error) {
+			name, err := stringArg("getenv", argumets[0])
+			if err != nil {
+				return value.Nil, err
+			}
+			if err := i.Check(capability.ENV, name); err != nil {
+				return value.Nil, err
+			}
+			if val, isSet := os.LookupEnv(name); isSet {
+				return value.String(val), nil
+			}
+			return value.Nil, nil
+		},
+	}
+}
+
+// Exec runs a command, e.g. `exec("git status")`, and returns its output. The command is split on spaces
+// and is not run by a shell. It requires the run capability for the program, which is killed when the Lox
+// program is stopped.
+func Exec[T Sandbox]() *native[T] {
+	return &native[T]{
+		arity: 1,
+		call: func(i T, argumets []value.Value) (value.Value, error) {
+			command, err := stringArg("exec", argumets[0])
+			if err != nil {
+				return value.Nil, err
+			}
+			fields := strings.Fields(command)
+			if len(fields) == 0 {
+				return value.Nil, fmt.Errorf("exec() expects a command.")
+			}
+			if err := i.Check(capability.RUN, fields[0]); err != nil {
+				return value.Nil, err
+			}
+			ctx := i.Context()
+			var stderr bytes.Buffer
+			cmd := exec.CommandContext(ctx, fields[0], fields[1:]...)
+			cmd.Stderr = &stderr
+			out, err := cmd.Output()
+			if ctx.Err() != nil {
+				return value.Nil, ctx.Err()
+			}
+			if err != nil {
+				return value.Nil, fmt.Errorf("%s: %v %s", fields[0], err, strings.TrimSpace(stderr.String()))
+			}
+			return value.String(string(out)), nil
+		},
+	}
+}
+
+func stringArg(name string, arg value.Value) (string, error) {
+	if !arg.IsString() {
+		return "", fmt.Errorf("%s() expects a string, got %s '%s'.", name, arg.Kind(), arg)
+	}
+	return arg.AsString(), nil
+}
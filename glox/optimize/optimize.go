@@ -0,0 +1,286 @@
+package optimize
+
+import (
+	"glox/ast"
+	"glox/token"
+	"glox/value"
+)
+
+// Optimizer rewrites a program before it runs: constant expressions are folded into literals, branches that
+// can never run are removed and so are empty blocks. Expressions that would fail at runtime (e.g. `1/0` or
+// `-"yes"`) are left untouched so that the error is still raised when, and only if, they are evaluated.
+type Optimizer struct{}
+
+func New() *Optimizer {
+	return &Optimizer{}
+}
+
+func Program(stmts []ast.Statement) []ast.Statement {
+	return New().statements(stmts)
+}
+
+func (o *Optimizer) statements(stmts []ast.Statement) []ast.Statement {
+	optimized := make([]ast.Statement, 0, len(stmts))
+	for _, stmt := range stmts {
+		if stmt = o.statement(stmt); stmt != nil {
+			optimized = append(optimized, stmt)
+		}
+	}
+	return optimized
+}
+
+// Returns nil when the statement can be removed.
+func (o *Optimizer) statement(stmt ast.Statement) ast.Statement {
+	if res, isStmt := stmt.Accept(o).(ast.Statement); isStmt {
+		return res
+	}
+	return nil
+}
+
+// Statements such as an if's branch or a loop's body cannot be removed, they become an empty block instead.
+func (o *Optimizer) required(stmt ast.Statement) ast.Statement {
+	if res := o.statement(stmt); res != nil {
+		return res
+	}
+	return ast.NewBlockStmt([]ast.Statement{})
+}
+
+func (o *Optimizer) expression(exp ast.Expression) ast.Expression {
+	return exp.Accept(o).(ast.Expression)
+}
+
+func (o *Optimizer) VisitPrintStmt(stmt *ast.PrintStmt) any {
+	stmt.Exp = o.expression(stmt.Exp)
+	return stmt
+}
+
+func (o *Optimizer) VisitExprStmt(stmt *ast.ExpressionStmt) any {
+	stmt.Exp = o.expression(stmt.Exp)
+	return stmt
+}
+
+func (o *Optimizer) VisitLetStmt(stmt *ast.LetStmt) any {
+	if stmt.Value != nil {
+		stmt.Value = o.expression(stmt.Value)
+	}
+	return stmt
+}
+
+func (o *Optimizer) VisitBlockStmt(stmt *ast.BlockStmt) any {
+	stmt.Stmts = o.statements(stmt.Stmts)
+	if len(stmt.Stmts) == 0 {
+		return nil
+	}
+	return stmt
+}
+
+func (o *Optimizer) VisitIfStmt(stmt *ast.IfStmt) any {
+	stmt.Condition = o.expression(stmt.Condition)
+	if cond, isConst := constant(stmt.Condition); isConst {
+		if cond.Truthy() {
+			return o.statement(stmt.Then)
+		} else if stmt.OrElse != nil {
+			return o.statement(stmt.OrElse)
+		}
+		return nil
+	}
+
+	stmt.Then = o.required(stmt.Then)
+	if stmt.OrElse != nil {
+		stmt.OrElse = o.statement(stmt.OrElse)
+	}
+	return stmt
+}
+
+func (o *Optimizer) VisitWhile(stmt *ast.WhileStmt) any {
+	stmt.Condition = o.expression(stmt.Condition)
+	if cond, isConst := constant(stmt.Condition); isConst && !cond.Truthy() {
+		return nil
+	}
+	stmt.Body = o.required(stmt.Body)
+	return stmt
+}
+
+func (o *Optimizer) VisitBranch(stmt *ast.BranchStmt) any {
+	return stmt
+}
+
+func (o *Optimizer) VisitFunction(stmt *ast.Function) any {
+	stmt.Body = o.statements(stmt.Body)
+	return stmt
+}
+
+func (o *Optimizer) VisitImport(stmt *ast.ImportStmt) any {
+	return stmt
+}
+
+func (o *Optimizer) VisitThrow(stmt *ast.ThrowStmt) any {
+	stmt.Value = o.expression(stmt.Value)
+	return stmt
+}
+
+// The blocks are kept even when they end up empty, `try {} finally { ... }` still runs its finally block.
+func (o *Optimizer) VisitTry(stmt *ast.TryStmt) any {
+	for _, block := range []*ast.BlockStmt{stmt.Body, stmt.Catch, stmt.Finally} {
+		if block != nil {
+			block.Stmts = o.statements(block.Stmts)
+		}
+	}
+	return stmt
+}
+
+func (o *Optimizer) VisitDefer(stmt *ast.DeferStmt) any {
+	o.VisitCall(stmt.Call)
+	return stmt
+}
+
+func (o *Optimizer) VisitBinary(exp *ast.Binary) any {
+	exp.Left = o.expression(exp.Left)
+	exp.Right = o.expression(exp.Right)
+
+	left, isLeftConst := constant(exp.Left)
+	right, isRightConst := constant(exp.Right)
+	if !isLeftConst || !isRightConst {
+		return exp
+	}
+
+	switch exp.Operator.Type {
+	case token.EQ_EQ:
+		return literal(value.Bool(value.Equal(left, right)))
+	case token.BANG_EQ:
+		return literal(value.Bool(!value.Equal(left, right)))
+	case token.PLUS:
+		if left.IsNumber() && right.IsNumber() {
+			return literal(value.Number(left.AsNumber() + right.AsNumber()))
+		}
+		if (left.IsString() || left.IsNumber()) && (right.IsString() || right.IsNumber()) {
+			return literal(value.String(left.String() + right.String()))
+		}
+		return exp
+	}
+
+	if !left.IsNumber() || !right.IsNumber() {
+		return exp
+	}
+	l, r := left.AsNumber(), right.AsNumber()
+	switch exp.Operator.Type {
+	case token.GREATER:
+		return literal(value.Bool(l > r))
+	case token.GREATER_EQ:
+		return literal(value.Bool(l >= r))
+	case token.LESS:
+		return literal(value.Bool(l < r))
+	case token.LESS_EQ:
+		return literal(value.Bool(l <= r))
+	case token.MINUS:
+		return literal(value.Number(l - r))
+	case token.ASTERISK:
+		return literal(value.Number(l * r))
+	case token.SLASH:
+		if r != 0 {
+			return literal(value.Number(l / r))
+		}
+	}
+	return exp
+}
+
+func (o *Optimizer) VisitUnary(exp *ast.Unary) any {
+	exp.Right = o.expression(exp.Right)
+
+	right, isConst := constant(exp.Right)
+	if !isConst {
+		return exp
+	}
+	switch exp.Operator.Type {
+	case token.BANG:
+		return literal(value.Bool(!right.Truthy()))
+	case token.MINUS:
+		if right.IsNumber() {
+			return literal(value.Number(-right.AsNumber()))
+		}
+	}
+	return exp
+}
+
+func (o *Optimizer) VisitGrouping(exp *ast.Grouping) any {
+	exp.Exp = o.expression(exp.Exp)
+	if _, isConst := constant(exp.Exp); isConst {
+		return exp.Exp
+	}
+	return exp
+}
+
+func (o *Optimizer) VisitLiteral(exp *ast.Literal) any {
+	return exp
+}
+
+func (o *Optimizer) VisitTernary(exp *ast.Ternary) any {
+	exp.Condition = o.expression(exp.Condition)
+	if cond, isConst := constant(exp.Condition); isConst {
+		if cond.Truthy() {
+			return o.expression(exp.Then)
+		}
+		return o.expression(exp.OrElse)
+	}
+
+	exp.Then = o.expression(exp.Then)
+	exp.OrElse = o.expression(exp.OrElse)
+	return exp
+}
+
+func (o *Optimizer) VisitVariable(exp *ast.Variable) any {
+	return exp
+}
+
+func (o *Optimizer) VisitAssignment(exp *ast.Assignment) any {
+	exp.Value = o.expression(exp.Value)
+	return exp
+}
+
+func (o *Optimizer) VisitLogical(exp *ast.Logical) any {
+	exp.Left = o.expression(exp.Left)
+	exp.Right = o.expression(exp.Right)
+
+	left, isConst := constant(exp.Left)
+	if !isConst {
+		return exp
+	}
+	if exp.Operator.Type == token.OR {
+		if left.Truthy() {
+			return exp.Left
+		}
+	} else if !left.Truthy() {
+		return exp.Left
+	}
+	return exp.Right
+}
+
+func (o *Optimizer) VisitCall(exp *ast.Call) any {
+	exp.Callee = o.expression(exp.Callee)
+	for i, arg := range exp.Args {
+		exp.Args[i] = o.expression(arg)
+	}
+	return exp
+}
+
+func (o *Optimizer) VisitGet(exp *ast.Get) any {
+	exp.Object = o.expression(exp.Object)
+	return exp
+}
+
+func (o *Optimizer) VisitSet(exp *ast.Set) any {
+	exp.Object = o.expression(exp.Object)
+	exp.Value = o.expression(exp.Value)
+	return exp
+}
+
+func constant(exp ast.Expression) (value.Value, bool) {
+	if lit, isLit := exp.(*ast.Literal); isLit {
+		return value.Of(lit.Value), true
+	}
+	return value.Nil, false
+}
+
+func literal(val value.Value) *ast.Literal {
+	return ast.NewLiteralExpression(val.Interface())
+}
@@ -0,0 +1,128 @@
+package optimize
+
+import (
+	"bytes"
+	"glox/ast"
+	"glox/interpreter"
+	"glox/lexer"
+	"glox/parser"
+	"strings"
+	"testing"
+)
+
+func TestFoldConstants(t *testing.T) {
+	tests := []struct {
+		code string
+		want any
+	}{
+		{code: `60 * 60 * 24;`, want: float64(86400)},
+		{code: `(1 + 2) * 3;`, want: float64(9)},
+		{code: `-(4 / 2);`, want: float64(-2)},
+		{code: `!nil;`, want: true},
+		{code: `"seconds: " + 60;`, want: "seconds: 60"},
+		{code: `1 < 2 == true;`, want: true},
+		{code: `"a" != "a";`, want: false},
+		{code: `false or "default";`, want: "default"},
+		{code: `nil and 12;`, want: nil},
+		{code: `1 > 2 ? "yes" : "no";`, want: "no"},
+	}
+
+	for _, test := range tests {
+		stmts := Program(parse(test.code, t))
+		if len(stmts) != 1 {
+			t.Fatalf("`%s` -> wrong number of statements. want=1 got=%d", test.code, len(stmts))
+		}
+		lit, isLit := stmts[0].(*ast.ExpressionStmt).Exp.(*ast.Literal)
+		if !isLit {
+			t.Fatalf("`%s` -> expression was not folded. got=%s", test.code, stmts[0].(*ast.ExpressionStmt).Exp)
+		}
+		if lit.Value != test.want {
+			t.Fatalf("`%s` -> wrong folded value. want=%v got=%v", test.code, test.want, lit.Value)
+		}
+	}
+}
+
+func TestPreserveRuntimeErrors(t *testing.T) {
+	tests := []string{
+		`1 / 0;`,
+		`-"yes";`,
+		`1 - false;`,
+		`"a" < "b";`,
+		`true + 1;`,
+		`x * 2;`,
+	}
+
+	for _, code := range tests {
+		stmts := Program(parse(code, t))
+		if _, isLit := stmts[0].(*ast.ExpressionStmt).Exp.(*ast.Literal); isLit {
+			t.Fatalf("`%s` -> must not be folded because it fails at runtime.", code)
+		}
+	}
+}
+
+func TestRemoveDeadCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want int
+	}{
+		{code: `if (false) { print "never"; }`, want: 0},
+		{code: `if (1 > 2) print "never"; else print "always";`, want: 1},
+		{code: `while (false) { print "never"; }`, want: 0},
+		{code: `{}`, want: 0},
+		{code: `{ { } if (nil) print 1; }`, want: 0},
+		{code: `let x = 1; if (x) {} else { print x; }`, want: 2},
+	}
+
+	for _, test := range tests {
+		stmts := Program(parse(test.code, t))
+		if len(stmts) != test.want {
+			t.Fatalf("`%s` -> wrong number of statements. want=%d got=%d", test.code, test.want, len(stmts))
+		}
+	}
+
+	stmts := Program(parse(`if (true) { print "always"; }`, t))
+	if _, isBlock := stmts[0].(*ast.BlockStmt); !isBlock {
+		t.Fatalf("the taken branch must replace the if statement. got=%T", stmts[0])
+	}
+}
+
+func TestSameOutput(t *testing.T) {
+	code := `
+let day = 60 * 60 * 24;
+print day;
+fun greet(name) {
+	if (!true) { print "unreachable"; }
+	print "hello " + name + (1 == 1 ? "!" : "?");
+}
+greet("anya");
+for (let i = 0; i < 2 * 2; i = i + 1) { if (false) {} print i; }
+print 10 / 0;
+`
+	want, wantErr := run(parse(code, t))
+	got, gotErr := run(Program(parse(code, t)))
+
+	if got != want {
+		t.Fatalf("optimized program printed different output.\nwant=%q\ngot=%q", want, got)
+	}
+	if gotErr != wantErr || !strings.Contains(gotErr, "division by zero") {
+		t.Fatalf("optimized program reported different errors.\nwant=%q\ngot=%q", wantErr, gotErr)
+	}
+}
+
+func parse(code string, t *testing.T) []ast.Statement {
+	tokens, err := lexer.New(code).Tokenize()
+	if err != nil {
+		t.Fatalf("`%s` -> failed to tokenize. got='%s'", code, err.Error())
+	}
+	stmts, err := parser.New(tokens).Parse()
+	if err != nil {
+		t.Fatalf("`%s` -> failed to parse. got='%s'", code, err.Error())
+	}
+	return stmts
+}
+
+func run(stmts []ast.Statement) (string, string) {
+	stdout, stderr := bytes.NewBufferString(""), bytes.NewBufferString("")
+	interpreter.New(stderr, stdout).Interpret(stmts)
+	return stdout.String(), stderr.String()
+}
@@ -16,6 +16,7 @@ type Parser struct {
 	tokens    []token.Token
 	position  int
 	loopLevel int
+	funLevel  int
 }
 
 func New(tokens []token.Token) *Parser {
@@ -49,6 +50,11 @@ func (p *Parser) declaration() (ast.Statement, error) {
 		return p.ifStatement()
 	} else if p.match(token.LET) {
 		return p.letDeclaration()
+	} else if p.match(token.IMPORT) {
+		return p.importDeclaration()
+	} else if p.checkWord("from") && p.tokens[p.position+1].Type == token.STRING {
+		p.advance()
+		return p.importDeclaration()
 	}
 	return p.statement()
 }
@@ -84,6 +90,8 @@ func (p *Parser) function(kind string) (ast.Statement, error) {
 		if _, err = p.consume(token.L_BRACE, "expected '{' before "+kind+" body"); err != nil {
 			return nil, err
 		}
+		p.funLevel++
+		defer func() { p.funLevel-- }()
 		body, err := p.block()
 		return ast.NewFunction(name, params, body), err
 	}
@@ -141,6 +149,47 @@ func (p *Parser) letDeclaration() (ast.Statement, error) {
 	return ast.NewLetStmt(tok, val), err
 }
 
+// importDecl -> "import" STRING "as" IDENTIFIER ";"
+// importDecl -> "from" STRING "import" IDENTIFIER ( "," IDENTIFIER )* ";"
+func (p *Parser) importDeclaration() (ast.Statement, error) {
+	keyword := p.previous()
+	path, err := p.consume(token.STRING, fmt.Sprintf("expected a module path after '%s'.", keyword.Lexeme))
+	if err != nil {
+		return nil, err
+	}
+
+	var alias token.Token
+	var names []token.Token
+	if keyword.Type == token.IMPORT {
+		if !p.checkWord("as") {
+			return nil, captureError(p.peek(), "expected 'as' after the module path.")
+		}
+		p.advance()
+		if alias, err = p.consume(token.IDENTIFIER, "expected a module name after 'as'."); err != nil {
+			return nil, err
+		}
+	} else {
+		if _, err = p.consume(token.IMPORT, "expected 'import' after the module path."); err != nil {
+			return nil, err
+		}
+		for {
+			name, err := p.consume(token.IDENTIFIER, "expected a name to import.")
+			if err != nil {
+				return nil, err
+			}
+			names = append(names, name)
+			if !p.match(token.COMMA) {
+				break
+			}
+		}
+	}
+
+	if _, err = p.consume(token.SEMICOLON, "expect ';' after import."); err != nil {
+		return nil, err
+	}
+	return ast.NewImportStmt(keyword, path, alias, names), nil
+}
+
 func (p *Parser) statement() (ast.Statement, error) {
 	if p.match(token.BREAK) || p.match(token.CONTINUE) {
 		tok := p.previous()
@@ -158,6 +207,12 @@ func (p *Parser) statement() (ast.Statement, error) {
 		return p.forStatement()
 	} else if p.match(token.PRINT) {
 		return p.printStatement()
+	} else if p.match(token.THROW) {
+		return p.throwStatement()
+	} else if p.match(token.TRY) {
+		return p.tryStatement()
+	} else if p.match(token.DEFER) {
+		return p.deferStatement()
 	} else if p.match(token.WHILE) {
 		p.loopLevel++
 		defer func() { p.loopLevel-- }()
@@ -277,6 +332,82 @@ func (p *Parser) printStatement() (ast.Statement, error) {
 
 }
 
+// throwStmt -> "throw" expression ";"
+func (p *Parser) throwStatement() (ast.Statement, error) {
+	keyword := p.previous()
+	val, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = p.consume(token.SEMICOLON, "expect ';' after thrown value."); err != nil {
+		return nil, err
+	}
+	return ast.NewThrowStmt(keyword, val), nil
+}
+
+// tryStmt -> "try" block ( "catch" "(" IDENTIFIER ")" block )? ( "finally" block )?
+func (p *Parser) tryStatement() (ast.Statement, error) {
+	body, err := p.blockStatement("try")
+	if err != nil {
+		return nil, err
+	}
+
+	var name token.Token
+	var catch, finally *ast.BlockStmt
+	if p.match(token.CATCH) {
+		if _, err = p.consume(token.L_PAREN, "expected '(' after 'catch'."); err != nil {
+			return nil, err
+		}
+		if name, err = p.consume(token.IDENTIFIER, "expected the name of the caught exception."); err != nil {
+			return nil, err
+		}
+		if _, err = p.consume(token.R_PAREN, "expected ')' after the caught exception."); err != nil {
+			return nil, err
+		}
+		if catch, err = p.blockStatement("catch"); err != nil {
+			return nil, err
+		}
+	}
+	if p.match(token.FINALLY) {
+		if finally, err = p.blockStatement("finally"); err != nil {
+			return nil, err
+		}
+	}
+	if catch == nil && finally == nil {
+		_, err = p.consume(token.CATCH, "expected 'catch' or 'finally' after the try block.")
+		return nil, err
+	}
+	return ast.NewTryStmt(body, name, catch, finally), nil
+}
+
+// deferStmt -> "defer" call ";"
+func (p *Parser) deferStatement() (ast.Statement, error) {
+	keyword := p.previous()
+	if p.funLevel == 0 {
+		return nil, exception.Runtime(keyword, "'defer' cannot be used outside of a function.")
+	}
+	exp, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	call, isCall := exp.(*ast.Call)
+	if !isCall {
+		return nil, exception.Runtime(keyword, "'defer' expects a function call.")
+	}
+	if _, err = p.consume(token.SEMICOLON, "expect ';' after deferred call."); err != nil {
+		return nil, err
+	}
+	return ast.NewDeferStmt(keyword, call), nil
+}
+
+func (p *Parser) blockStatement(kind string) (*ast.BlockStmt, error) {
+	if _, err := p.consume(token.L_BRACE, "expected '{' before "+kind+" block."); err != nil {
+		return nil, err
+	}
+	stmts, err := p.block()
+	return ast.NewBlockStmt(stmts), err
+}
+
 func (p *Parser) expressionStatement() (ast.Statement, error) {
 	exp, err := p.expression()
 	if err != nil {
@@ -304,6 +435,8 @@ func (p *Parser) assignment() (ast.Expression, error) {
 
 		if variable, isVar := exp.(*ast.Variable); isVar {
 			return ast.NewAssignment(variable.Name, val), err
+		} else if get, isGet := exp.(*ast.Get); isGet {
+			return ast.NewSet(get.Object, get.Name, val), err
 		}
 
 		err = exception.Runtime(equals, "invalid assignment target.")
@@ -404,6 +537,12 @@ func (p *Parser) check(tokType token.TokenType) bool {
 	return p.peek().Type == tokType
 }
 
+// Tells whether the next token is the identifier word. `from` and `as` are only keywords in import statements,
+// they remain valid names everywhere else.
+func (p *Parser) checkWord(word string) bool {
+	return p.check(token.IDENTIFIER) && p.peek().Lexeme == word
+}
+
 func (p *Parser) advance() token.Token {
 	if !p.isAtEnd() {
 		p.position++
@@ -497,6 +636,12 @@ func (p *Parser) call() (ast.Expression, error) {
 				if err != nil {
 					break
 				}
+			} else if p.match(token.DOT) {
+				var name token.Token
+				if name, err = p.consume(token.IDENTIFIER, "expected property name after '.'."); err != nil {
+					break
+				}
+				expr = ast.NewGet(expr, name)
 			} else {
 				break
 			}
@@ -568,8 +713,8 @@ func (p *Parser) consume(tokType token.TokenType, message string) (token.Token,
 
 func captureError(tok token.Token, msg string) error {
 	if tok.Type == token.EOF {
-		return exception.Generic(tok.Line, " at end", msg)
+		return exception.AtEnd(exception.Generic(tok.Line, tok.Column, " at end", msg))
 	}
 
-	return exception.Generic(tok.Line, "'"+tok.Lexeme+"'", msg)
+	return exception.Generic(tok.Line, tok.Column, "'"+tok.Lexeme+"'", msg)
 }
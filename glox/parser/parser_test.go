@@ -853,6 +853,161 @@ func TestParseCall(t *testing.T) {
 
 }
 
+func TestParseProperty(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"order.total;", "(get (variable order) . total)"},
+		{"order.customer.name;", "(get (get (variable order) . customer) . name)"},
+		{"order.addItem(1);", "(call (get (variable order) . addItem)(arguments (literal  1 )))"},
+		{"order.total = 1;", "(set (variable order) . total (literal  1 ))"},
+		{"find().name = x = 2;", "(set (call (variable find)(arguments )) . name (assignment (x(literal  2 ))))"},
+	}
+
+	for _, test := range tests {
+		tokens, err := lexer.New(test.code).Tokenize()
+		if err != nil {
+			t.Fatalf("failed to tokenize code `%s`", test.code)
+		}
+		stmts, err := New(tokens).Parse()
+		if err != nil {
+			t.Fatalf("failed to parse code `%s`. got=%v", test.code, err)
+		}
+		stmt, isOk := stmts[0].(*ast.ExpressionStmt)
+		if !isOk {
+			t.Fatalf("stmts[0] is not a *ast.ExpressionStmt. got=%T", stmts[0])
+		}
+		if got := stmt.Exp.String(); got != test.want {
+			t.Fatalf("wrong expression for `%s`. want=%s got=%s", test.code, test.want, got)
+		}
+	}
+
+	for _, code := range []string{"order.;", "order.1;", "order.total + 1 = 2;"} {
+		tokens, _ := lexer.New(code).Tokenize()
+		if _, err := New(tokens).Parse(); err == nil {
+			t.Fatalf("expected a parse error for `%s`", code)
+		}
+	}
+}
+
+func TestParseImport(t *testing.T) {
+	tests := []struct {
+		code  string
+		path  string
+		alias string
+		names []string
+	}{
+		{`import "lib/math.lox" as m;`, "lib/math.lox", "m", nil},
+		{`from "lib/math.lox" import square;`, "lib/math.lox", "", []string{"square"}},
+		{`from "lib/math.lox" import square, pi;`, "lib/math.lox", "", []string{"square", "pi"}},
+	}
+
+	for _, test := range tests {
+		tokens, err := lexer.New(test.code).Tokenize()
+		if err != nil {
+			t.Fatalf("failed to tokenize code `%s`", test.code)
+		}
+		stmts, err := New(tokens).Parse()
+		if err != nil {
+			t.Fatalf("failed to parse code `%s`. got=%v", test.code, err)
+		}
+		stmt, isOk := stmts[0].(*ast.ImportStmt)
+		if !isOk {
+			t.Fatalf("stmts[0] is not a *ast.ImportStmt. got=%T", stmts[0])
+		}
+		if stmt.Path.Literal != test.path || stmt.Alias.Lexeme != test.alias || len(stmt.Names) != len(test.names) {
+			t.Fatalf("wrong import for `%s`. got=%+v", test.code, stmt)
+		}
+		for n, name := range test.names {
+			if stmt.Names[n].Lexeme != name {
+				t.Fatalf("wrong name %d for `%s`. want=%s got=%s", n, test.code, name, stmt.Names[n].Lexeme)
+			}
+		}
+	}
+
+	// Outside of imports `from` and `as` are names.
+	for _, code := range []string{`let from = 1; let as = from; from = as + 1;`, `fun copy(from, as) { print from + as; }`, `from;`, `import "m.lox" as from;`, `from "m.lox" import as;`} {
+		tokens, _ := lexer.New(code).Tokenize()
+		if _, err := New(tokens).Parse(); err != nil {
+			t.Fatalf("failed to parse code `%s`. got=%v", code, err)
+		}
+	}
+
+	for _, code := range []string{`import m;`, `import "m.lox";`, `import "m.lox" like m;`, `import "m.lox" as;`, `from "m.lox" import;`, `from "m.lox" import a,;`, `from "m.lox" import a`} {
+		tokens, _ := lexer.New(code).Tokenize()
+		if _, err := New(tokens).Parse(); err == nil {
+			t.Fatalf("expected a parse error for `%s`", code)
+		}
+	}
+}
+
+func TestParseTry(t *testing.T) {
+	tests := []struct {
+		code       string
+		name       string
+		hasCatch   bool
+		hasFinally bool
+	}{
+		{`try { throw "x"; } catch (e) { print e; }`, "e", true, false},
+		{`try { print 1; } finally { print 2; }`, "", false, true},
+		{`try {} catch (err) {} finally {}`, "err", true, true},
+	}
+
+	for _, test := range tests {
+		tokens, err := lexer.New(test.code).Tokenize()
+		if err != nil {
+			t.Fatalf("failed to tokenize code `%s`", test.code)
+		}
+		stmts, err := New(tokens).Parse()
+		if err != nil {
+			t.Fatalf("failed to parse code `%s`. got=%v", test.code, err)
+		}
+		stmt, isOk := stmts[0].(*ast.TryStmt)
+		if !isOk {
+			t.Fatalf("stmts[0] is not a *ast.TryStmt. got=%T", stmts[0])
+		}
+		if stmt.Name.Lexeme != test.name || (stmt.Catch != nil) != test.hasCatch || (stmt.Finally != nil) != test.hasFinally {
+			t.Fatalf("wrong try statement for `%s`. got=%+v", test.code, stmt)
+		}
+	}
+
+	tokens, _ := lexer.New(`throw "x" + 1;`).Tokenize()
+	stmts, err := New(tokens).Parse()
+	if err != nil {
+		t.Fatalf("failed to parse throw statement. got=%v", err)
+	}
+	if throw, isOk := stmts[0].(*ast.ThrowStmt); !isOk || throw.Value.String() != `(binary (literal  x ) + (literal  1 ))` {
+		t.Fatalf("wrong throw statement. got=%+v", stmts[0])
+	}
+
+	for _, code := range []string{`try {}`, `try print 1; catch (e) {}`, `try {} catch {}`, `try {} catch (1) {}`, `try {} catch (e) print e;`, `throw;`, `throw 1`} {
+		tokens, _ := lexer.New(code).Tokenize()
+		if _, err := New(tokens).Parse(); err == nil {
+			t.Fatalf("expected a parse error for `%s`", code)
+		}
+	}
+}
+
+func TestParseDefer(t *testing.T) {
+	tokens, _ := lexer.New(`fun f() { defer log("done"); }`).Tokenize()
+	stmts, err := New(tokens).Parse()
+	if err != nil {
+		t.Fatalf("failed to parse defer statement. got=%v", err)
+	}
+	stmt, isOk := stmts[0].(*ast.Function).Body[0].(*ast.DeferStmt)
+	if !isOk || stmt.Call.String() != `(call (variable log)(arguments (literal  done )))` {
+		t.Fatalf("wrong defer statement. got=%+v", stmts[0].(*ast.Function).Body[0])
+	}
+
+	for _, code := range []string{`defer log(1);`, `{ defer log(1); }`, `fun f() { defer log; }`, `fun f() { defer 1 + 2; }`, `fun f() { defer log(1) }`} {
+		tokens, _ := lexer.New(code).Tokenize()
+		if _, err := New(tokens).Parse(); err == nil {
+			t.Fatalf("expected a parse error for `%s`", code)
+		}
+	}
+}
+
 func TestParseWhile(t *testing.T) {
 	tests := []struct {
 		code string
@@ -0,0 +1,223 @@
+package resolver
+
+import (
+	"glox/ast"
+)
+
+// Resolver walks a program before it runs and tells every local variable which environment (depth) and
+// which slot of that environment holds its value, so the interpreter never looks locals up by name.
+// Anything declared outside of a block or a function is global and stays looked up by name.
+type Resolver struct {
+	scopes []*scope
+}
+
+type scope struct {
+	slots map[string]int
+}
+
+func New() *Resolver {
+	return &Resolver{scopes: []*scope{}}
+}
+
+func (r *Resolver) Resolve(stmts []ast.Statement) {
+	for _, stmt := range stmts {
+		r.resolveStmt(stmt)
+	}
+}
+
+func (r *Resolver) VisitPrintStmt(stmt *ast.PrintStmt) any {
+	r.resolveExpr(stmt.Exp)
+	return nil
+}
+
+func (r *Resolver) VisitExprStmt(stmt *ast.ExpressionStmt) any {
+	r.resolveExpr(stmt.Exp)
+	return nil
+}
+
+func (r *Resolver) VisitLetStmt(stmt *ast.LetStmt) any {
+	// The initializer is resolved before the name is declared, so `let age = age + 1;` in a block reads
+	// the enclosing `age`.
+	if stmt.Value != nil {
+		r.resolveExpr(stmt.Value)
+	}
+	stmt.Binding = r.declare(stmt.Name.Lexeme)
+	return nil
+}
+
+func (r *Resolver) VisitBlockStmt(stmt *ast.BlockStmt) any {
+	r.beginScope()
+	r.Resolve(stmt.Stmts)
+	stmt.Locals = r.endScope()
+	return nil
+}
+
+func (r *Resolver) VisitIfStmt(stmt *ast.IfStmt) any {
+	r.resolveExpr(stmt.Condition)
+	r.resolveStmt(stmt.Then)
+	if stmt.OrElse != nil {
+		r.resolveStmt(stmt.OrElse)
+	}
+	return nil
+}
+
+func (r *Resolver) VisitWhile(stmt *ast.WhileStmt) any {
+	r.resolveExpr(stmt.Condition)
+	r.resolveStmt(stmt.Body)
+	return nil
+}
+
+func (r *Resolver) VisitBranch(stmt *ast.BranchStmt) any {
+	return nil
+}
+
+func (r *Resolver) VisitFunction(stmt *ast.Function) any {
+	// Declared before its body is resolved so that the function can call itself.
+	stmt.Binding = r.declare(stmt.Name.Lexeme)
+
+	r.beginScope()
+	for _, param := range stmt.Params {
+		r.declare(param.Lexeme)
+	}
+	r.Resolve(stmt.Body)
+	stmt.Locals = r.endScope()
+	return nil
+}
+
+func (r *Resolver) VisitImport(stmt *ast.ImportStmt) any {
+	stmt.Bindings = stmt.Bindings[:0]
+	for _, name := range stmt.Declared() {
+		stmt.Bindings = append(stmt.Bindings, r.declare(name.Lexeme))
+	}
+	return nil
+}
+
+func (r *Resolver) VisitThrow(stmt *ast.ThrowStmt) any {
+	r.resolveExpr(stmt.Value)
+	return nil
+}
+
+func (r *Resolver) VisitTry(stmt *ast.TryStmt) any {
+	r.resolveStmt(stmt.Body)
+	if stmt.Catch != nil {
+		// The exception is declared in the catch block's own scope, like a parameter.
+		r.beginScope()
+		r.declare(stmt.Name.Lexeme)
+		r.Resolve(stmt.Catch.Stmts)
+		stmt.Catch.Locals = r.endScope()
+	}
+	if stmt.Finally != nil {
+		r.resolveStmt(stmt.Finally)
+	}
+	return nil
+}
+
+func (r *Resolver) VisitDefer(stmt *ast.DeferStmt) any {
+	r.resolveExpr(stmt.Call)
+	return nil
+}
+
+func (r *Resolver) VisitBinary(exp *ast.Binary) any {
+	r.resolveExpr(exp.Left)
+	r.resolveExpr(exp.Right)
+	return nil
+}
+
+func (r *Resolver) VisitUnary(exp *ast.Unary) any {
+	r.resolveExpr(exp.Right)
+	return nil
+}
+
+func (r *Resolver) VisitGrouping(exp *ast.Grouping) any {
+	r.resolveExpr(exp.Exp)
+	return nil
+}
+
+func (r *Resolver) VisitLiteral(exp *ast.Literal) any {
+	return nil
+}
+
+func (r *Resolver) VisitTernary(exp *ast.Ternary) any {
+	r.resolveExpr(exp.Condition)
+	r.resolveExpr(exp.Then)
+	r.resolveExpr(exp.OrElse)
+	return nil
+}
+
+func (r *Resolver) VisitVariable(exp *ast.Variable) any {
+	exp.Binding = r.lookup(exp.Name.Lexeme)
+	return nil
+}
+
+func (r *Resolver) VisitAssignment(exp *ast.Assignment) any {
+	r.resolveExpr(exp.Value)
+	exp.Binding = r.lookup(exp.Name.Lexeme)
+	return nil
+}
+
+func (r *Resolver) VisitLogical(exp *ast.Logical) any {
+	r.resolveExpr(exp.Left)
+	r.resolveExpr(exp.Right)
+	return nil
+}
+
+func (r *Resolver) VisitCall(exp *ast.Call) any {
+	r.resolveExpr(exp.Callee)
+	for _, arg := range exp.Args {
+		r.resolveExpr(arg)
+	}
+	return nil
+}
+
+func (r *Resolver) VisitGet(exp *ast.Get) any {
+	r.resolveExpr(exp.Object)
+	return nil
+}
+
+func (r *Resolver) VisitSet(exp *ast.Set) any {
+	r.resolveExpr(exp.Value)
+	r.resolveExpr(exp.Object)
+	return nil
+}
+
+func (r *Resolver) resolveStmt(stmt ast.Statement) {
+	stmt.Accept(r)
+}
+
+func (r *Resolver) resolveExpr(exp ast.Expression) {
+	exp.Accept(r)
+}
+
+func (r *Resolver) beginScope() {
+	r.scopes = append(r.scopes, &scope{slots: make(map[string]int)})
+}
+
+// Closes the innermost scope and returns the number of slots it used.
+func (r *Resolver) endScope() int {
+	last := r.scopes[len(r.scopes)-1]
+	r.scopes = r.scopes[:len(r.scopes)-1]
+	return len(last.slots)
+}
+
+// Declares a name in the innermost scope. Re-declaring a name in the same scope reuses its slot.
+func (r *Resolver) declare(name string) ast.Binding {
+	if len(r.scopes) == 0 {
+		return ast.Binding{}
+	}
+	current := r.scopes[len(r.scopes)-1]
+	slot, isOk := current.slots[name]
+	if !isOk {
+		slot = len(current.slots)
+		current.slots[name] = slot
+	}
+	return ast.Binding{Local: true, Depth: 0, Slot: slot}
+}
+
+func (r *Resolver) lookup(name string) ast.Binding {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if slot, isOk := r.scopes[i].slots[name]; isOk {
+			return ast.Binding{Local: true, Depth: len(r.scopes) - 1 - i, Slot: slot}
+		}
+	}
+	return ast.Binding{}
+}
@@ -0,0 +1,91 @@
+package resolver
+
+import (
+	"glox/ast"
+	"glox/lexer"
+	"glox/parser"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	code := `
+let global = 1;
+{
+	let a = global;
+	let b = 2;
+	{
+		let a = a + b;
+		print a;
+	}
+}
+fun add(x, y) {
+	let sum = x + y;
+	print sum;
+}
+{
+	from "m.lox" import min, max;
+	print max;
+}
+`
+	tokens, err := lexer.New(code).Tokenize()
+	if err != nil {
+		t.Fatalf("failed to tokenize code. got='%s'", err.Error())
+	}
+	stmts, err := parser.New(tokens).Parse()
+	if err != nil {
+		t.Fatalf("failed to parse code. got='%s'", err.Error())
+	}
+	New().Resolve(stmts)
+
+	let := stmts[0].(*ast.LetStmt)
+	if let.Binding.Local {
+		t.Fatalf("top-level declarations must be global. got=%+v", let.Binding)
+	}
+
+	outer := stmts[1].(*ast.BlockStmt)
+	if outer.Locals != 2 {
+		t.Fatalf("wrong number of locals for block. want=2 got=%d", outer.Locals)
+	}
+	if init := outer.Stmts[0].(*ast.LetStmt).Value.(*ast.Variable); init.Binding.Local {
+		t.Fatalf("'global' must be resolved as a global. got=%+v", init.Binding)
+	}
+	if b := outer.Stmts[1].(*ast.LetStmt); b.Binding != (ast.Binding{Local: true, Depth: 0, Slot: 1}) {
+		t.Fatalf("wrong binding for 'b'. got=%+v", b.Binding)
+	}
+
+	inner := outer.Stmts[2].(*ast.BlockStmt)
+	shadow := inner.Stmts[0].(*ast.LetStmt)
+	sum := shadow.Value.(*ast.Binary)
+	tests := []struct {
+		name string
+		got  ast.Binding
+		want ast.Binding
+	}{
+		{name: "shadowing 'a'", got: shadow.Binding, want: ast.Binding{Local: true, Depth: 0, Slot: 0}},
+		{name: "initializer 'a'", got: sum.Left.(*ast.Variable).Binding, want: ast.Binding{Local: true, Depth: 1, Slot: 0}},
+		{name: "initializer 'b'", got: sum.Right.(*ast.Variable).Binding, want: ast.Binding{Local: true, Depth: 1, Slot: 1}},
+		{name: "printed 'a'", got: inner.Stmts[1].(*ast.PrintStmt).Exp.(*ast.Variable).Binding, want: ast.Binding{Local: true, Depth: 0, Slot: 0}},
+	}
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Fatalf("wrong binding for %s. want=%+v got=%+v", test.name, test.want, test.got)
+		}
+	}
+
+	fn := stmts[2].(*ast.Function)
+	if fn.Locals != 3 {
+		t.Fatalf("wrong number of locals for function. want=3 got=%d", fn.Locals)
+	}
+	params := fn.Body[0].(*ast.LetStmt).Value.(*ast.Binary)
+	if y := params.Right.(*ast.Variable).Binding; y != (ast.Binding{Local: true, Depth: 0, Slot: 1}) {
+		t.Fatalf("wrong binding for parameter 'y'. got=%+v", y)
+	}
+
+	imports := stmts[3].(*ast.BlockStmt)
+	if imports.Locals != 2 {
+		t.Fatalf("wrong number of locals for block with imports. want=2 got=%d", imports.Locals)
+	}
+	if max := imports.Stmts[1].(*ast.PrintStmt).Exp.(*ast.Variable).Binding; max != imports.Stmts[0].(*ast.ImportStmt).Bindings[1] {
+		t.Fatalf("wrong binding for imported 'max'. got=%+v", max)
+	}
+}
@@ -0,0 +1,54 @@
+package source
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// Names of the sources that are not read from a file.
+const (
+	STDIN = "<stdin>"
+	EVAL  = "<eval>" // Code given on the command line.
+)
+
+// UTF-8 byte order mark, some editors write it at the start of files.
+const BOM = "\uFEFF"
+
+// Source is a Lox program along with the name it is reported with, e.g. `script.lox:12:5`.
+type Source struct {
+	Name string
+	Code string
+}
+
+// New prepares code for the lexer: the UTF-8 byte order mark is removed and so is a leading `#!` line,
+// whose line break is kept so that lines are still counted from the top of the file.
+func New(name string, code string) *Source {
+	code = strings.TrimPrefix(code, BOM)
+	if strings.HasPrefix(code, "#!") {
+		if end := strings.IndexByte(code, '\n'); end >= 0 {
+			code = code[end:]
+		} else {
+			code = ""
+		}
+	}
+	return &Source{Name: name, Code: code}
+}
+
+// Read reads r until its end, r can be a pipe or a terminal.
+func Read(name string, r io.Reader) (*Source, error) {
+	code, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return New(name, string(code)), nil
+}
+
+// Load reads the file at path, which is also the name of the source.
+func Load(path string) (*Source, error) {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return New(path, string(code)), nil
+}
@@ -0,0 +1,61 @@
+package source
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"print 1;", "print 1;"},
+		{BOM + "print 1;", "print 1;"},
+		{"#!/usr/bin/env glox\nprint 1;", "\nprint 1;"},
+		{BOM + "#!/usr/bin/env glox\r\nprint 1;", "\nprint 1;"},
+		{"#!/usr/bin/env glox", ""},
+		{"print 1; #!", "print 1; #!"},
+	}
+
+	for _, test := range tests {
+		got := New("script.lox", test.code)
+		if got.Code != test.want || got.Name != "script.lox" {
+			t.Fatalf("wrong source for %q. want=%q got=%q", test.code, test.want, got.Code)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	code := strings.Repeat("print \"a long line to read in several chunks\";\n", 10000)
+	// OneByteReader reads one byte at a time like a slow pipe would.
+	got, err := Read(STDIN, iotest.OneByteReader(strings.NewReader(code)))
+	if err != nil {
+		t.Fatalf("failed to read source. got=%v", err)
+	}
+	if got.Code != code || got.Name != STDIN {
+		t.Fatalf("source was not read fully. want=%d bytes got=%d", len(code), len(got.Code))
+	}
+
+	if _, err := Read(STDIN, iotest.ErrReader(os.ErrClosed)); err == nil {
+		t.Fatalf("expected the reader's error.")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.lox")
+	if err := os.WriteFile(path, []byte("#!/usr/bin/env glox\nprint 1;"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Load(path)
+	if err != nil || got.Name != path || got.Code != "\nprint 1;" {
+		t.Fatalf("wrong source for %s. got=%+v err=%v", path, got, err)
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.lox")); err == nil {
+		t.Fatalf("expected an error for a missing file.")
+	}
+}
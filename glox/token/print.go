@@ -0,0 +1,28 @@
+package token
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// Fprint writes one row per token with its line, type, lexeme and literal. Comments are printed as rows of
+// their own, before the token they lead or after the token they trail.
+func Fprint(w io.Writer, tokens []Token) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "LINE\tTYPE\tLEXEME\tLITERAL\n")
+	for _, tok := range tokens {
+		for _, trivia := range tok.Leading {
+			fmt.Fprintf(tw, "%d\t%s\t%q\t(leading trivia)\n", trivia.Line, trivia.Type, trivia.Lexeme)
+		}
+		literal := ""
+		if tok.Literal != nil {
+			literal = fmt.Sprintf("%#v", tok.Literal)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%q\t%s\n", tok.Line, tok.Type, tok.Lexeme, literal)
+		for _, trivia := range tok.Trailing {
+			fmt.Fprintf(tw, "%d\t%s\t%q\t(trailing trivia)\n", trivia.Line, trivia.Type, trivia.Lexeme)
+		}
+	}
+	return tw.Flush()
+}
@@ -1,5 +1,7 @@
 package token
 
+import "sort"
+
 type TokenType string
 
 const (
@@ -52,6 +54,12 @@ const (
 	TRUE           = "TRUE"
 	LET            = "LET"
 	WHILE          = "WHILE"
+	IMPORT         = "IMPORT" // `from` and `as` are identifiers, the parser only treats them as keywords in imports.
+	THROW          = "THROW"
+	TRY            = "TRY"
+	CATCH          = "CATCH"
+	FINALLY        = "FINALLY"
+	DEFER          = "DEFER"
 	SLASH_SLASH    = "SLASH_SLASH"
 	SLASK_ASTERISK = "SLASH_ASTERISK"
 
@@ -60,10 +68,29 @@ const (
 )
 
 type Token struct {
-	Type    TokenType
-	Literal any
-	Lexeme  string
-	Line    int
+	Type     TokenType `json:"type"`
+	Literal  any       `json:"literal,omitempty"`
+	Lexeme   string    `json:"lexeme"`
+	Line     int       `json:"line"`
+	Column   int       `json:"column,omitempty"`   // Column of the first character counted in runes from 1, 0 when unknown.
+	Leading  []Trivia  `json:"leading,omitempty"`  // Comments between the previous token's line and this token.
+	Trailing []Trivia  `json:"trailing,omitempty"` // Comments following this token on the same line.
+}
+
+// Trivia is a comment attached to a token. Comments are kept out of the token stream so that the parser
+// never sees them, formatters and documentation tools can still find them on the tokens.
+type Trivia struct {
+	Type   TokenType `json:"type"` // Either SLASH_SLASH or SLASK_ASTERISK
+	Lexeme string    `json:"lexeme"`
+	Line   int       `json:"line"` // Line on which the comment starts.
+}
+
+// The comment without its delimiters.
+func (t Trivia) Text() string {
+	if t.Type == SLASK_ASTERISK {
+		return t.Lexeme[2 : len(t.Lexeme)-2]
+	}
+	return t.Lexeme[2:]
 }
 
 var keywords = map[string]TokenType{
@@ -86,6 +113,12 @@ var keywords = map[string]TokenType{
 	"var":      LET,
 	"break":    BREAK,
 	"continue": CONTINUE,
+	"import":   IMPORT,
+	"throw":    THROW,
+	"try":      TRY,
+	"catch":    CATCH,
+	"finally":  FINALLY,
+	"defer":    DEFER,
 }
 
 func LookupIdentifier(keyword string) TokenType {
@@ -97,6 +130,16 @@ func LookupIdentifier(keyword string) TokenType {
 	return IDENTIFIER
 }
 
+// Keywords returns every reserved word, sorted.
+func Keywords() []string {
+	words := make([]string, 0, len(keywords))
+	for word := range keywords {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
+}
+
 func IsLoopController(tok TokenType) bool {
 	return tok == CONTINUE || tok == BREAK
 }
@@ -1,6 +1,9 @@
 package token
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestLookupIdentifier(t *testing.T) {
 	tests := []struct {
@@ -25,6 +28,14 @@ func TestLookupIdentifier(t *testing.T) {
 		{word: "super", expected: SUPER},
 		{word: "let", expected: LET},
 		{word: "var", expected: LET},
+		{word: "import", expected: IMPORT},
+		{word: "from", expected: IDENTIFIER},
+		{word: "as", expected: IDENTIFIER},
+		{word: "throw", expected: THROW},
+		{word: "try", expected: TRY},
+		{word: "catch", expected: CATCH},
+		{word: "finally", expected: FINALLY},
+		{word: "defer", expected: DEFER},
 		{word: "func", expected: IDENTIFIER},
 		{word: "struct", expected: IDENTIFIER},
 		{word: "interface", expected: IDENTIFIER},
@@ -41,6 +52,21 @@ func TestLookupIdentifier(t *testing.T) {
 	}
 }
 
+func TestKeywords(t *testing.T) {
+	words := Keywords()
+	if len(words) != len(keywords) {
+		t.Fatalf("wrong number of keywords. want=%d got=%d", len(keywords), len(words))
+	}
+	for i, word := range words {
+		if LookupIdentifier(word) == IDENTIFIER {
+			t.Fatalf("%q is not a keyword.", word)
+		}
+		if i > 0 && words[i-1] > word {
+			t.Fatalf("keywords are not sorted. got=%v", words)
+		}
+	}
+}
+
 func TestIsLoopController(t *testing.T) {
 	tests := []struct {
 		tok      TokenType
@@ -68,3 +94,37 @@ func TestIsLoopController(t *testing.T) {
 		}
 	}
 }
+
+func TestFprint(t *testing.T) {
+	tokens := []Token{
+		{Type: LET, Lexeme: "let", Line: 1, Leading: []Trivia{{Type: SLASH_SLASH, Lexeme: "// doc", Line: 1}}},
+		{Type: NUMBER, Lexeme: "12", Literal: 12.0, Line: 2, Trailing: []Trivia{{Type: SLASK_ASTERISK, Lexeme: "/* twelve */", Line: 2}}},
+		{Type: ILLEGAL, Lexeme: "~", Line: 2},
+		{Type: EOF, Lexeme: "", Line: 3},
+	}
+
+	var out strings.Builder
+	if err := Fprint(&out, tokens); err != nil {
+		t.Fatalf("failed to print tokens. got error='%s'", err.Error())
+	}
+
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	want := [][]string{
+		{"LINE", "TYPE", "LEXEME", "LITERAL"},
+		{"1", "SLASH_SLASH", `"// doc"`, "(leading trivia)"},
+		{"1", "LET", `"let"`},
+		{"2", "NUM", `"12"`, "12"},
+		{"2", "SLASH_ASTERISK", `"/* twelve */"`, "(trailing trivia)"},
+		{"2", "ILLEGAL", `"~"`},
+		{"3", "EOF", `""`},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("wrong number of rows. want=%d got=%d\n%s", len(want), len(lines), out.String())
+	}
+	for i, line := range lines {
+		got := strings.Fields(line)
+		if strings.Join(got, " ") != strings.Join(want[i], " ") {
+			t.Fatalf("wrong row %d. want=%q got=%q", i, want[i], got)
+		}
+	}
+}
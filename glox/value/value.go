@@ -0,0 +1,180 @@
+package value
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+type Kind uint8
+
+const (
+	NIL Kind = iota
+	BOOL
+	NUMBER
+	STRING
+	OBJECT
+)
+
+func (k Kind) String() string {
+	switch k {
+	case NIL:
+		return "nil"
+	case BOOL:
+		return "boolean"
+	case NUMBER:
+		return "number"
+	case STRING:
+		return "string"
+	case OBJECT:
+		return "object"
+	}
+	return fmt.Sprintf("Kind(%d)", k)
+}
+
+// Value is a Lox value. Booleans and numbers live in `num` so that arithmetic never allocates, strings and
+// objects (e.g. functions) are kept in `ref`.
+type Value struct {
+	kind Kind
+	num  float64
+	ref  any
+}
+
+var Nil = Value{}
+
+func Bool(b bool) Value {
+	if b {
+		return Value{kind: BOOL, num: 1}
+	}
+	return Value{kind: BOOL}
+}
+
+func Number(n float64) Value {
+	return Value{kind: NUMBER, num: n}
+}
+
+func String(s string) Value {
+	return Value{kind: STRING, ref: s}
+}
+
+func Object(obj fmt.Stringer) Value {
+	return Value{kind: OBJECT, ref: obj}
+}
+
+// Converts a Go value as produced by the lexer (float64, string, bool or nil) into a Value.
+// Other numeric types are converted to numbers and `fmt.Stringer`s become objects.
+func Of(val any) Value {
+	switch v := val.(type) {
+	case nil:
+		return Nil
+	case Value:
+		return v
+	case bool:
+		return Bool(v)
+	case float64:
+		return Number(v)
+	case int:
+		return Number(float64(v))
+	case string:
+		// Reuses the interface holding the string instead of boxing it again.
+		return Value{kind: STRING, ref: val}
+	case fmt.Stringer:
+		return Object(v)
+	}
+	panic(fmt.Sprintf("%T cannot be converted to a Lox value.", val))
+}
+
+func (v Value) Kind() Kind {
+	return v.kind
+}
+
+func (v Value) IsNil() bool {
+	return v.kind == NIL
+}
+
+func (v Value) IsNumber() bool {
+	return v.kind == NUMBER
+}
+
+func (v Value) IsString() bool {
+	return v.kind == STRING
+}
+
+func (v Value) AsBool() bool {
+	return v.kind == BOOL && v.num != 0
+}
+
+func (v Value) AsNumber() float64 {
+	return v.num
+}
+
+func (v Value) AsString() string {
+	str, _ := v.ref.(string)
+	return str
+}
+
+func (v Value) AsObject() any {
+	if v.kind != OBJECT {
+		return nil
+	}
+	return v.ref
+}
+
+// Converts the value back into a plain Go value: nil, bool, float64, string or the object itself.
+func (v Value) Interface() any {
+	switch v.kind {
+	case BOOL:
+		return v.AsBool()
+	case NUMBER:
+		return v.num
+	case STRING, OBJECT:
+		return v.ref
+	}
+	return nil
+}
+
+// Only `nil` and `false` are falsey, everything else is truthy.
+func (v Value) Truthy() bool {
+	switch v.kind {
+	case NIL:
+		return false
+	case BOOL:
+		return v.num != 0
+	}
+	return true
+}
+
+func (v Value) String() string {
+	switch v.kind {
+	case NIL:
+		return "<nil>"
+	case BOOL:
+		return strconv.FormatBool(v.AsBool())
+	case NUMBER:
+		return strconv.FormatFloat(v.num, 'g', -1, 64)
+	case STRING:
+		return v.AsString()
+	}
+	return v.ref.(fmt.Stringer).String()
+}
+
+// Values of different kinds are never equal. Unlike Go, NaN is equal to NaN.
+func Equal(l Value, r Value) bool {
+	if l.kind != r.kind {
+		return false
+	}
+	switch l.kind {
+	case NIL:
+		return true
+	case BOOL:
+		return l.num == r.num
+	case NUMBER:
+		if math.IsNaN(l.num) && math.IsNaN(r.num) {
+			return true
+		}
+		return l.num == r.num
+	case STRING:
+		return l.AsString() == r.AsString()
+	}
+	return l.ref == r.ref
+}
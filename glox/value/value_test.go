@@ -0,0 +1,97 @@
+package value
+
+import (
+	"math"
+	"testing"
+)
+
+type object struct{ name string }
+
+func (o *object) String() string {
+	return "<" + o.name + ">"
+}
+
+func TestOf(t *testing.T) {
+	obj := &object{name: "fn"}
+	tests := []struct {
+		input any
+		kind  Kind
+		str   string
+	}{
+		{input: nil, kind: NIL, str: "<nil>"},
+		{input: true, kind: BOOL, str: "true"},
+		{input: false, kind: BOOL, str: "false"},
+		{input: 12, kind: NUMBER, str: "12"},
+		{input: 3.14, kind: NUMBER, str: "3.14"},
+		{input: 1e21, kind: NUMBER, str: "1e+21"},
+		{input: "anya", kind: STRING, str: "anya"},
+		{input: obj, kind: OBJECT, str: "<fn>"},
+		{input: Number(7), kind: NUMBER, str: "7"},
+	}
+
+	for _, test := range tests {
+		got := Of(test.input)
+		if got.Kind() != test.kind {
+			t.Fatalf("wrong kind for %v. want=%v got=%v", test.input, test.kind, got.Kind())
+		}
+		if got.String() != test.str {
+			t.Fatalf("wrong string for %v. want=%q got=%q", test.input, test.str, got.String())
+		}
+	}
+
+	if Of(obj).AsObject() != obj {
+		t.Fatalf("object values must keep their reference. got=%v", Of(obj).AsObject())
+	}
+	if got := Of("anya").Interface(); got != "anya" {
+		t.Fatalf("wrong Go value for string. got=%v", got)
+	}
+	if got := Of(12).Interface(); got != float64(12) {
+		t.Fatalf("wrong Go value for number. got=%v", got)
+	}
+}
+
+func TestTruthy(t *testing.T) {
+	tests := []struct {
+		val  Value
+		want bool
+	}{
+		{val: Nil, want: false},
+		{val: Bool(false), want: false},
+		{val: Bool(true), want: true},
+		{val: Number(0), want: true},
+		{val: String(""), want: true},
+		{val: Object(&object{}), want: true},
+	}
+
+	for _, test := range tests {
+		if got := test.val.Truthy(); got != test.want {
+			t.Fatalf("wrong truthiness for %v. want=%v got=%v", test.val, test.want, got)
+		}
+	}
+}
+
+func TestEqual(t *testing.T) {
+	obj := &object{name: "fn"}
+	tests := []struct {
+		left  Value
+		right Value
+		want  bool
+	}{
+		{left: Nil, right: Nil, want: true},
+		{left: Nil, right: Bool(false), want: false},
+		{left: Bool(true), right: Bool(true), want: true},
+		{left: Number(1), right: Number(1.0000001), want: false},
+		{left: Number(math.NaN()), right: Number(math.NaN()), want: true},
+		{left: Number(1), right: String("1"), want: false},
+		{left: String("yes"), right: String("yes"), want: true},
+		{left: String("yes"), right: String("Yes"), want: false},
+		{left: Object(obj), right: Object(obj), want: true},
+		{left: Object(obj), right: Object(&object{name: "fn"}), want: false},
+	}
+
+	for _, test := range tests {
+		if got := Equal(test.left, test.right); got != test.want {
+			t.Fatalf("Equal(%v, %v) is wrong. want=%v got=%v", test.left, test.right, test.want, got)
+		}
+	}
+}